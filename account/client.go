@@ -4,7 +4,11 @@ import (
 	"context"
 
 	"github.com/rajan-marasini/ecom-microservice/account/pb"
+	"github.com/rajan-marasini/ecom-microservice/errs"
+	"github.com/rajan-marasini/ecom-microservice/grpcclient"
 	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 type Client struct {
@@ -12,8 +16,11 @@ type Client struct {
 	service pb.AccountServiceClient
 }
 
-func NewClient(url string) (*Client, error) {
-	conn, err := grpc.NewClient(url, grpc.WithInsecure())
+// NewClient connects to the service at url, which may list several
+// addresses; see grpcclient.Dial. Reads are retried while the service is
+// unavailable.
+func NewClient(url string, opts grpcclient.Options) (*Client, error) {
+	conn, err := grpcclient.Dial(url, pb.AccountService_ServiceDesc.ServiceName, opts, "GetAccountByID", "GetAccounts", "VerifyToken")
 	if err != nil {
 		return nil, err
 	}
@@ -30,21 +37,132 @@ func (c *Client) Close() {
 	c.conn.Close()
 }
 
-func (c *Client) PostAccount(ctx context.Context, name string) (*Account, error) {
+// Health reports an error unless the account service is serving.
+func (c *Client) Health(ctx context.Context) error {
+	res, err := healthpb.NewHealthClient(c.conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		return errs.FromGRPC(err)
+	}
+	if res.Status != healthpb.HealthCheckResponse_SERVING {
+		return errs.New(errs.Unavailable, "account service is %s", res.Status)
+	}
+	return nil
+}
+
+func (c *Client) PostAccount(ctx context.Context, name, email, phone, password string) (*Account, error) {
 	r, err := c.service.PostAccount(
 		ctx,
 		&pb.PostAccountRequest{
-			Name: name,
+			Name:     name,
+			Email:    email,
+			Phone:    phone,
+			Password: password,
 		})
 
 	if err != nil {
-		return nil, err
+		return nil, errs.FromGRPC(err)
 	}
 
-	return &Account{
-		ID:   r.Account.Id,
-		Name: r.Account.Name,
-	}, nil
+	a := accountFromProto(r.Account)
+	return &a, nil
+}
+
+func (c *Client) Login(ctx context.Context, email, password string) (*Account, *Tokens, error) {
+	r, err := c.service.Login(ctx, &pb.LoginRequest{
+		Email:    email,
+		Password: password,
+	})
+	if err != nil {
+		return nil, nil, errs.FromGRPC(err)
+	}
+
+	a, tokens := accountFromProto(r.Account), tokensFromProto(r.Tokens)
+	return &a, &tokens, nil
+}
+
+func (c *Client) RefreshToken(ctx context.Context, refreshToken string) (*Account, *Tokens, error) {
+	r, err := c.service.RefreshToken(ctx, &pb.RefreshTokenRequest{RefreshToken: refreshToken})
+	if err != nil {
+		return nil, nil, errs.FromGRPC(err)
+	}
+
+	a, tokens := accountFromProto(r.Account), tokensFromProto(r.Tokens)
+	return &a, &tokens, nil
+}
+
+// VerifyToken returns the active account accessToken was issued for.
+func (c *Client) VerifyToken(ctx context.Context, accessToken string) (*Account, error) {
+	r, err := c.service.VerifyToken(ctx, &pb.VerifyTokenRequest{AccessToken: accessToken})
+	if err != nil {
+		return nil, errs.FromGRPC(err)
+	}
+
+	a := accountFromProto(r.Account)
+	return &a, nil
+}
+
+// UpdateAccount changes the fields of update that are not nil.
+func (c *Client) UpdateAccount(ctx context.Context, id string, update AccountUpdate) (*Account, error) {
+	req := &pb.UpdateAccountRequest{
+		Id:         id,
+		UpdateMask: &fieldmaskpb.FieldMask{},
+	}
+	if update.Name != nil {
+		req.Name = *update.Name
+		req.UpdateMask.Paths = append(req.UpdateMask.Paths, "name")
+	}
+	if update.Email != nil {
+		req.Email = *update.Email
+		req.UpdateMask.Paths = append(req.UpdateMask.Paths, "email")
+	}
+	if update.Phone != nil {
+		req.Phone = *update.Phone
+		req.UpdateMask.Paths = append(req.UpdateMask.Paths, "phone")
+	}
+	if update.Status != nil {
+		req.Status = pb.AccountStatus(*update.Status)
+		req.UpdateMask.Paths = append(req.UpdateMask.Paths, "status")
+	}
+	if update.Roles != nil {
+		for _, role := range *update.Roles {
+			req.Roles = append(req.Roles, string(role))
+		}
+		req.UpdateMask.Paths = append(req.UpdateMask.Paths, "roles")
+	}
+
+	r, err := c.service.UpdateAccount(ctx, req)
+	if err != nil {
+		return nil, errs.FromGRPC(err)
+	}
+
+	a := accountFromProto(r.Account)
+	return &a, nil
+}
+
+// ChangePassword sets the password of an account, checking currentPassword
+// unless it is nil.
+func (c *Client) ChangePassword(ctx context.Context, id string, currentPassword *string, newPassword string) (*Account, error) {
+	r, err := c.service.ChangePassword(ctx, &pb.ChangePasswordRequest{
+		Id:              id,
+		CurrentPassword: currentPassword,
+		NewPassword:     newPassword,
+	})
+	if err != nil {
+		return nil, errs.FromGRPC(err)
+	}
+
+	a := accountFromProto(r.Account)
+	return &a, nil
+}
+
+func (c *Client) DeleteAccount(ctx context.Context, id string) (*Account, error) {
+	r, err := c.service.DeleteAccount(ctx, &pb.DeleteAccountRequest{Id: id})
+	if err != nil {
+		return nil, errs.FromGRPC(err)
+	}
+
+	a := accountFromProto(r.Account)
+	return &a, nil
 }
 
 func (c *Client) GetAccountByID(ctx context.Context, id string) (*Account, error) {
@@ -54,16 +172,14 @@ func (c *Client) GetAccountByID(ctx context.Context, id string) (*Account, error
 			Id: id,
 		})
 	if err != nil {
-		return nil, err
+		return nil, errs.FromGRPC(err)
 	}
 
-	return &Account{
-		ID:   r.Account.Id,
-		Name: r.Account.Name,
-	}, nil
+	a := accountFromProto(r.Account)
+	return &a, nil
 }
 
-func (c *Client) GetAccounts(ctx context.Context, take uint64, skip uint64) ([]Account, error) {
+func (c *Client) GetAccounts(ctx context.Context, skip uint64, take uint64) ([]Account, error) {
 	r, err := c.service.GetAccounts(
 		ctx,
 		&pb.GetAccountsRequest{
@@ -71,18 +187,40 @@ func (c *Client) GetAccounts(ctx context.Context, take uint64, skip uint64) ([]A
 			Take: take,
 		})
 	if err != nil {
-		return nil, err
+		return nil, errs.FromGRPC(err)
 	}
 
 	accounts := []Account{}
 
 	for _, a := range r.Accounts {
-		accounts = append(accounts, Account{
-			ID:   a.Id,
-			Name: a.Name,
-		})
+		accounts = append(accounts, accountFromProto(a))
 	}
 
 	return accounts, nil
 
 }
+
+func accountFromProto(ap *pb.Account) Account {
+	a := Account{
+		ID:     ap.Id,
+		Name:   ap.Name,
+		Email:  ap.Email,
+		Phone:  ap.Phone,
+		Status: Status(ap.Status),
+	}
+	for _, role := range ap.Roles {
+		a.Roles = append(a.Roles, Role(role))
+	}
+	a.CreatedAt.UnmarshalBinary(ap.CreatedAt)
+	a.UpdatedAt.UnmarshalBinary(ap.UpdatedAt)
+	return a
+}
+
+func tokensFromProto(tp *pb.Tokens) Tokens {
+	t := Tokens{
+		AccessToken:  tp.AccessToken,
+		RefreshToken: tp.RefreshToken,
+	}
+	t.ExpiresAt.UnmarshalBinary(tp.ExpiresAt)
+	return t
+}
@@ -1,16 +1,44 @@
 package main
 
 import (
+	"context"
 	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/kelseyhightower/envconfig"
 	"github.com/rajan-marasini/ecom-microservice/account"
+	"github.com/rajan-marasini/ecom-microservice/logging"
+	"github.com/rajan-marasini/ecom-microservice/metrics"
+	"github.com/rajan-marasini/ecom-microservice/migrate"
+	"github.com/rajan-marasini/ecom-microservice/mtls"
+	"github.com/rajan-marasini/ecom-microservice/tracing"
 	"github.com/tinrab/retry"
 )
 
+const minJWTSecretLength = 32
+
 type Config struct {
-	DatabaseURL string `envconfig:"DATABASE_URL"`
+	DatabaseURL    string `envconfig:"DATABASE_URL"`
+	StorageBackend string `envconfig:"STORAGE_BACKEND" default:"postgres"`
+	AutoMigrate    bool   `envconfig:"AUTO_MIGRATE" default:"true"`
+	// JWTSecret signs access and refresh tokens. Use at least 32 random
+	// bytes; every replica must share it.
+	JWTSecret       string        `envconfig:"JWT_SECRET"`
+	AccessTokenTTL  time.Duration `envconfig:"ACCESS_TOKEN_TTL" default:"15m"`
+	RefreshTokenTTL time.Duration `envconfig:"REFRESH_TOKEN_TTL" default:"720h"`
+	// TLS enables mutual TLS with TLS_CERT_FILE, TLS_KEY_FILE and TLS_CA_FILE.
+	TLS mtls.Config `envconfig:"TLS"`
+	// ShutdownTimeout bounds how long in-flight requests may run after SIGTERM.
+	ShutdownTimeout time.Duration `envconfig:"SHUTDOWN_TIMEOUT" default:"15s"`
+	// MetricsPort serves Prometheus metrics at /metrics.
+	MetricsPort int `envconfig:"METRICS_PORT" default:"9090"`
+	// Log sets the lowest level logged with LOG_LEVEL (debug, info, warn or
+	// error) and the format with LOG_FORMAT (json or text).
+	Log logging.Config `envconfig:"LOG"`
 }
 
 func main() {
@@ -21,16 +49,84 @@ func main() {
 		log.Fatal(err)
 	}
 
-	var r account.Repository
-	retry.ForeverSleep(2*time.Second, func(_ int) (err error) {
-		r, err = account.NewPostgresRepository(cfg.DatabaseURL)
-		if err != nil {
-			log.Println(err)
+	if err := logging.Init(cfg.Log, "account"); err != nil {
+		log.Fatal(err)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate.Run(context.Background(), cfg.DatabaseURL, account.Migrations, os.Args[2:], os.Stdout); err != nil {
+			logging.Fatal("migration failed", "error", err)
 		}
 		return
-	})
-	defer r.Close()
-	log.Println("Listening on port 8080...")
-	s := account.NewService(r)
-	log.Fatal(account.ListenGRPC(s, 8080))
+	}
+
+	// Anyone who knows the secret can sign tokens for any account, so a
+	// short or well-known one must not get as far as serving.
+	if len(cfg.JWTSecret) < minJWTSecretLength {
+		logging.Fatal("JWT_SECRET must be set to at least 32 random bytes")
+	}
+
+	creds, err := mtls.Load(cfg.TLS)
+	if err != nil {
+		logging.Fatal("loading TLS credentials failed", "error", err)
+	}
+
+	shutdownTracing, err := tracing.Init(context.Background(), "account")
+	if err != nil {
+		logging.Fatal("initializing tracing failed", "error", err)
+	}
+
+	var r account.Repository
+	switch cfg.StorageBackend {
+	case "memory":
+		r = account.NewMemoryRepository()
+	case "postgres":
+		retry.ForeverSleep(2*time.Second, func(_ int) (err error) {
+			if cfg.AutoMigrate {
+				if err = migrate.Run(context.Background(), cfg.DatabaseURL, account.Migrations, []string{"up"}, log.Writer()); err != nil {
+					slog.Error("migrating database failed, retrying", "error", err)
+					return
+				}
+			}
+			r, err = account.NewPostgresRepository(cfg.DatabaseURL)
+			if err != nil {
+				slog.Error("connecting to storage failed, retrying", "error", err)
+			}
+			return
+		})
+	default:
+		logging.Fatal("unknown storage backend", "backend", cfg.StorageBackend)
+	}
+
+	tokens := account.NewTokenIssuer([]byte(cfg.JWTSecret), cfg.AccessTokenTTL, cfg.RefreshTokenTTL)
+	s := account.NewService(r, tokens)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		slog.Error("metrics server stopped", "error", metrics.ListenAndServe(cfg.MetricsPort))
+	}()
+
+	srv, err := account.ListenGRPC(ctx, s, 8080, creds, r.Ping)
+	if err != nil {
+		r.Close()
+		logging.Fatal("starting gRPC server failed", "error", err)
+	}
+	slog.Info("listening", "port", 8080)
+
+	select {
+	case <-ctx.Done():
+		slog.Info("shutting down")
+	case <-srv.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	err = srv.Shutdown(shutdownCtx)
+	r.Close()
+	shutdownTracing(shutdownCtx)
+	if err != nil {
+		logging.Fatal("shutdown failed", "error", err)
+	}
 }
@@ -0,0 +1,156 @@
+package account
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rajan-marasini/ecom-microservice/errs"
+)
+
+type memoryRepository struct {
+	mu       sync.RWMutex
+	accounts map[string]Account
+}
+
+// NewMemoryRepository returns a Repository that keeps accounts in process
+// memory. It mirrors the Postgres implementation, including its ordering and
+// pagination, and is meant for demos and tests.
+func NewMemoryRepository() Repository {
+	return &memoryRepository{
+		accounts: map[string]Account{},
+	}
+}
+
+func (r *memoryRepository) Close() {
+}
+
+func (r *memoryRepository) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (r *memoryRepository) PutAccount(ctx context.Context, a Account) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.accounts[a.ID]; ok {
+		return errs.New(errs.AlreadyExists, "account %s already exists", a.ID)
+	}
+	if r.emailTaken(a) {
+		return errs.New(errs.AlreadyExists, "email %s is already registered", a.Email)
+	}
+	r.accounts[a.ID] = copyAccount(a)
+
+	return nil
+}
+
+func (r *memoryRepository) UpdateAccount(ctx context.Context, a Account) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.accounts[a.ID]; !ok {
+		return errs.New(errs.NotFound, "account %s not found", a.ID)
+	}
+	if r.emailTaken(a) {
+		return errs.New(errs.AlreadyExists, "email %s is already registered", a.Email)
+	}
+	a = copyAccount(a)
+	a.PasswordHash = r.accounts[a.ID].PasswordHash
+	r.accounts[a.ID] = a
+
+	return nil
+}
+
+func (r *memoryRepository) UpdatePasswordHash(ctx context.Context, id string, hash []byte, updatedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	a, ok := r.accounts[id]
+	if !ok {
+		return errs.New(errs.NotFound, "account %s not found", id)
+	}
+	a.PasswordHash, a.UpdatedAt = slices.Clone(hash), updatedAt
+	r.accounts[id] = a
+
+	return nil
+}
+
+// emailTaken reports whether another account already uses a's email, like the
+// unique index on accounts.email.
+func (r *memoryRepository) emailTaken(a Account) bool {
+	if a.Email == "" {
+		return false
+	}
+	for _, other := range r.accounts {
+		if other.ID != a.ID && other.Email == a.Email {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memoryRepository) GetAccountById(ctx context.Context, id string) (*Account, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	a, ok := r.accounts[id]
+	if !ok {
+		return nil, errs.New(errs.NotFound, "account %s not found", id)
+	}
+	a = copyAccount(a)
+
+	return &a, nil
+}
+
+func (r *memoryRepository) GetAccountByEmail(ctx context.Context, email string) (*Account, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, a := range r.accounts {
+		if a.Email != "" && a.Email == email {
+			a = copyAccount(a)
+			return &a, nil
+		}
+	}
+
+	return nil, errs.New(errs.NotFound, "no account with email %s", email)
+}
+
+func (r *memoryRepository) ListAccounts(ctx context.Context, skip uint64, take uint64) ([]Account, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	accounts := make([]Account, 0, len(r.accounts))
+	for _, a := range r.accounts {
+		accounts = append(accounts, copyAccount(a))
+	}
+
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].ID > accounts[j].ID
+	})
+
+	return paginate(accounts, skip, take), nil
+}
+
+// copyAccount detaches the roles and password hash so callers can't mutate
+// stored accounts.
+func copyAccount(a Account) Account {
+	a.Roles = slices.Clone(a.Roles)
+	a.PasswordHash = slices.Clone(a.PasswordHash)
+	return a
+}
+
+func paginate(accounts []Account, skip uint64, take uint64) []Account {
+	if skip >= uint64(len(accounts)) {
+		return []Account{}
+	}
+	accounts = accounts[skip:]
+
+	if take < uint64(len(accounts)) {
+		accounts = accounts[:take]
+	}
+
+	return accounts
+}
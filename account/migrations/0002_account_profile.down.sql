@@ -0,0 +1,6 @@
+DROP INDEX accounts_email_key;
+ALTER TABLE accounts DROP COLUMN updated_at;
+ALTER TABLE accounts DROP COLUMN created_at;
+ALTER TABLE accounts DROP COLUMN status;
+ALTER TABLE accounts DROP COLUMN phone;
+ALTER TABLE accounts DROP COLUMN email;
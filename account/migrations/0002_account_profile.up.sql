@@ -0,0 +1,9 @@
+-- Accounts created before this migration have no email, so the column stays
+-- nullable; the unique index still rejects duplicates among the others.
+ALTER TABLE accounts ADD COLUMN email TEXT;
+ALTER TABLE accounts ADD COLUMN phone TEXT NOT NULL DEFAULT '';
+ALTER TABLE accounts ADD COLUMN status TEXT NOT NULL DEFAULT 'active'
+    CHECK (status IN ('active', 'suspended', 'closed'));
+ALTER TABLE accounts ADD COLUMN created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now();
+ALTER TABLE accounts ADD COLUMN updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now();
+CREATE UNIQUE INDEX accounts_email_key ON accounts (email);
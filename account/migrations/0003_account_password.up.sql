@@ -0,0 +1,3 @@
+-- Accounts created before this migration have no password and cannot log in
+-- until one is set.
+ALTER TABLE accounts ADD COLUMN password_hash TEXT;
@@ -0,0 +1 @@
+ALTER TABLE accounts ADD COLUMN roles TEXT[] NOT NULL DEFAULT '{customer}';
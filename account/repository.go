@@ -3,14 +3,26 @@ package account
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
+	"github.com/rajan-marasini/ecom-microservice/errs"
+	"github.com/rajan-marasini/ecom-microservice/metrics"
+	"github.com/rajan-marasini/ecom-microservice/tracing"
 )
 
 type Repository interface {
 	Close()
+	// Ping reports an error when the storage cannot be reached.
+	Ping(ctx context.Context) error
 	PutAccount(ctx context.Context, a Account) error
+	// UpdateAccount saves every field of a except its password hash, which
+	// only UpdatePasswordHash changes.
+	UpdateAccount(ctx context.Context, a Account) error
+	UpdatePasswordHash(ctx context.Context, id string, hash []byte, updatedAt time.Time) error
 	GetAccountById(ctx context.Context, id string) (*Account, error)
+	GetAccountByEmail(ctx context.Context, email string) (*Account, error)
 	ListAccounts(ctx context.Context, skip uint64, take uint64) ([]Account, error)
 }
 
@@ -19,14 +31,16 @@ type postgresRepository struct {
 }
 
 func NewPostgresRepository(url string) (Repository, error) {
-	db, err := sql.Open("postgres", url)
+	connector, err := pq.NewConnector(url)
 	if err != nil {
 		return nil, err
 	}
+	db := sql.OpenDB(tracing.Connector(connector, "postgresql"))
 
 	if err := db.Ping(); err != nil {
 		return nil, err
 	}
+	metrics.RegisterDB(db, "account")
 
 	return &postgresRepository{db}, nil
 }
@@ -35,24 +49,87 @@ func (r *postgresRepository) Close() {
 	r.db.Close()
 }
 
-func (r *postgresRepository) Ping() error {
-	return r.db.Ping()
+func (r *postgresRepository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
 }
 
 func (r *postgresRepository) PutAccount(ctx context.Context, a Account) error {
-	query := "INSERT INTO accounts(id, name) VALUES($1, $2)"
-	_, err := r.db.ExecContext(ctx, query, a.ID, a.Name)
+	query := `INSERT INTO accounts(id, name, email, phone, status, created_at, updated_at, password_hash, roles)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		a.ID,
+		a.Name,
+		nullEmail(a.Email),
+		a.Phone,
+		a.Status.String(),
+		a.CreatedAt,
+		a.UpdatedAt,
+		sql.NullString{String: string(a.PasswordHash), Valid: len(a.PasswordHash) != 0},
+		pq.Array(a.Roles),
+	)
+
+	return uniqueError(err, a)
+}
+
+func (r *postgresRepository) UpdateAccount(ctx context.Context, a Account) error {
+	query := `UPDATE accounts SET name=$2, email=$3, phone=$4, status=$5, updated_at=$6, roles=$7 WHERE id=$1`
+	res, err := r.db.ExecContext(ctx, query, a.ID, a.Name, nullEmail(a.Email), a.Phone, a.Status.String(), a.UpdatedAt, pq.Array(a.Roles))
+	if err != nil {
+		return uniqueError(err, a)
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errs.New(errs.NotFound, "account %s not found", a.ID)
+	}
 
-	return err
+	return nil
 }
 
+func (r *postgresRepository) UpdatePasswordHash(ctx context.Context, id string, hash []byte, updatedAt time.Time) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE accounts SET password_hash=$2, updated_at=$3 WHERE id=$1`, id, string(hash), updatedAt)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errs.New(errs.NotFound, "account %s not found", id)
+	}
+
+	return nil
+}
+
+const accountColumns = "id, name, email, phone, status, created_at, updated_at, password_hash, roles"
+
 func (r *postgresRepository) GetAccountById(ctx context.Context, id string) (*Account, error) {
-	query := "SELECT id, name FROM accounts WHERE id=$1"
+	query := "SELECT " + accountColumns + " FROM accounts WHERE id=$1"
 	row := r.db.QueryRowContext(ctx, query, id)
 
-	a := &Account{}
+	a, err := scanAccount(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errs.New(errs.NotFound, "account %s not found", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (r *postgresRepository) GetAccountByEmail(ctx context.Context, email string) (*Account, error) {
+	query := "SELECT " + accountColumns + " FROM accounts WHERE email=$1"
+	row := r.db.QueryRowContext(ctx, query, email)
 
-	if err := row.Scan(&a.ID, &a.Name); err != nil {
+	a, err := scanAccount(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errs.New(errs.NotFound, "no account with email %s", email)
+	}
+	if err != nil {
 		return nil, err
 	}
 
@@ -60,7 +137,7 @@ func (r *postgresRepository) GetAccountById(ctx context.Context, id string) (*Ac
 }
 
 func (r *postgresRepository) ListAccounts(ctx context.Context, skip uint64, take uint64) ([]Account, error) {
-	query := "SELECT id, name FROM accounts ORDER BY id DESC OFFSET $1 LIMIT $2"
+	query := "SELECT " + accountColumns + " FROM accounts ORDER BY id DESC OFFSET $1 LIMIT $2"
 
 	rows, err := r.db.QueryContext(ctx, query, skip, take)
 	if err != nil {
@@ -71,10 +148,11 @@ func (r *postgresRepository) ListAccounts(ctx context.Context, skip uint64, take
 	accounts := []Account{}
 
 	for rows.Next() {
-		a := &Account{}
-		if err := rows.Scan(&a.ID, &a.Name); err == nil {
-			accounts = append(accounts, *a)
+		a, err := scanAccount(rows)
+		if err != nil {
+			return nil, err
 		}
+		accounts = append(accounts, *a)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
@@ -82,3 +160,51 @@ func (r *postgresRepository) ListAccounts(ctx context.Context, skip uint64, take
 
 	return accounts, nil
 }
+
+func scanAccount(row interface{ Scan(dest ...any) error }) (*Account, error) {
+	a := &Account{}
+	var (
+		email        sql.NullString
+		status       string
+		passwordHash sql.NullString
+		roles        pq.StringArray
+	)
+
+	if err := row.Scan(&a.ID, &a.Name, &email, &a.Phone, &status, &a.CreatedAt, &a.UpdatedAt, &passwordHash, &roles); err != nil {
+		return nil, err
+	}
+
+	for _, role := range roles {
+		a.Roles = append(a.Roles, Role(role))
+	}
+
+	var err error
+	a.Email = email.String
+	if passwordHash.Valid {
+		a.PasswordHash = []byte(passwordHash.String)
+	}
+	if a.Status, err = parseStatus(status); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// nullEmail stores a missing email as NULL, which the unique index allows
+// more than once.
+func nullEmail(email string) sql.NullString {
+	return sql.NullString{String: email, Valid: email != ""}
+}
+
+// uniqueError reports which unique constraint a write of a violated.
+func uniqueError(err error, a Account) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != "23505" {
+		return err
+	}
+
+	if pqErr.Constraint == "accounts_email_key" {
+		return errs.New(errs.AlreadyExists, "email %s is already registered", a.Email)
+	}
+	return errs.New(errs.AlreadyExists, "account %s already exists", a.ID)
+}
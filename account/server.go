@@ -4,9 +4,14 @@ package account
 import (
 	"context"
 	"fmt"
-	"net"
 
 	"github.com/rajan-marasini/ecom-microservice/account/pb"
+	"github.com/rajan-marasini/ecom-microservice/errs"
+	"github.com/rajan-marasini/ecom-microservice/grpcserver"
+	"github.com/rajan-marasini/ecom-microservice/logging"
+	"github.com/rajan-marasini/ecom-microservice/metrics"
+	"github.com/rajan-marasini/ecom-microservice/mtls"
+	"github.com/rajan-marasini/ecom-microservice/tracing"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 )
@@ -16,46 +21,135 @@ type grpcServer struct {
 	pb.UnimplementedAccountServiceServer
 }
 
-func ListenGRPC(s Service, port int) error {
-	list, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
-	if err != nil {
-		return err
-	}
-
-	serv := grpc.NewServer()
+// ListenGRPC serves s on port. The server reports itself healthy while checks
+// pass.
+func ListenGRPC(ctx context.Context, s Service, port int, creds *mtls.Credentials, checks ...grpcserver.Check) (*grpcserver.Server, error) {
+	serv := grpc.NewServer(
+		creds.ServerOption(),
+		tracing.ServerOption(),
+		grpc.ChainUnaryInterceptor(
+			metrics.UnaryServerInterceptor,
+			errs.UnaryServerInterceptor,
+			logging.UnaryServerInterceptor,
+		),
+	)
 	pb.RegisterAccountServiceServer(serv, &grpcServer{
 		service: s,
 	})
 	reflection.Register(serv)
-	return serv.Serve(list)
-
+	grpcserver.RegisterHealth(ctx, serv, pb.AccountService_ServiceDesc.ServiceName, checks...)
+	return grpcserver.Start(ctx, serv, port)
 }
 
 func (s *grpcServer) PostAccount(ctx context.Context, r *pb.PostAccountRequest) (*pb.PostAccountResponse, error) {
-	a, err := s.service.PostAccount(ctx, r.Name)
+	a, err := s.service.PostAccount(ctx, r.Name, r.Email, r.Phone, r.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.PostAccountResponse{Account: accountToProto(*a)}, nil
+}
+
+func (s *grpcServer) Login(ctx context.Context, r *pb.LoginRequest) (*pb.LoginResponse, error) {
+	a, tokens, err := s.service.Login(ctx, r.Email, r.Password)
 	if err != nil {
 		return nil, err
 	}
 
-	return &pb.PostAccountResponse{
-		Account: &pb.Account{
-			Id:   a.ID,
-			Name: a.Name,
-		},
+	return &pb.LoginResponse{
+		Account: accountToProto(*a),
+		Tokens:  tokensToProto(*tokens),
 	}, nil
 }
 
+func (s *grpcServer) RefreshToken(ctx context.Context, r *pb.RefreshTokenRequest) (*pb.RefreshTokenResponse, error) {
+	a, tokens, err := s.service.RefreshToken(ctx, r.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.RefreshTokenResponse{
+		Account: accountToProto(*a),
+		Tokens:  tokensToProto(*tokens),
+	}, nil
+}
+
+func (s *grpcServer) VerifyToken(ctx context.Context, r *pb.VerifyTokenRequest) (*pb.VerifyTokenResponse, error) {
+	a, err := s.service.VerifyToken(ctx, r.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.VerifyTokenResponse{Account: accountToProto(*a)}, nil
+}
+
+func (s *grpcServer) UpdateAccount(ctx context.Context, r *pb.UpdateAccountRequest) (*pb.UpdateAccountResponse, error) {
+	update := AccountUpdate{}
+	violations := []errs.Violation{}
+
+	for _, path := range r.UpdateMask.GetPaths() {
+		switch path {
+		case "name":
+			update.Name = &r.Name
+		case "email":
+			update.Email = &r.Email
+		case "phone":
+			update.Phone = &r.Phone
+		case "status":
+			status := Status(r.Status)
+			update.Status = &status
+		case "roles":
+			roles := []Role{}
+			for _, role := range r.Roles {
+				roles = append(roles, Role(role))
+			}
+			update.Roles = &roles
+		default:
+			violations = append(violations, errs.Violation{
+				Field:       "updateMask",
+				Description: fmt.Sprintf("unknown field %q", path),
+			})
+		}
+	}
+	if len(r.UpdateMask.GetPaths()) == 0 {
+		violations = append(violations, errs.Violation{Field: "updateMask", Description: "must not be empty"})
+	}
+	if len(violations) > 0 {
+		return nil, errs.Invalid("invalid update mask", violations...)
+	}
+
+	a, err := s.service.UpdateAccount(ctx, r.Id, update)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.UpdateAccountResponse{Account: accountToProto(*a)}, nil
+}
+
+func (s *grpcServer) ChangePassword(ctx context.Context, r *pb.ChangePasswordRequest) (*pb.ChangePasswordResponse, error) {
+	a, err := s.service.ChangePassword(ctx, r.Id, r.CurrentPassword, r.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.ChangePasswordResponse{Account: accountToProto(*a)}, nil
+}
+
+func (s *grpcServer) DeleteAccount(ctx context.Context, r *pb.DeleteAccountRequest) (*pb.DeleteAccountResponse, error) {
+	a, err := s.service.DeleteAccount(ctx, r.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.DeleteAccountResponse{Account: accountToProto(*a)}, nil
+}
+
 func (s *grpcServer) GetAccountByID(ctx context.Context, r *pb.GetAccountByIDRequest) (*pb.GetAccountByIDResponse, error) {
 	a, err := s.service.GetAccountByID(ctx, r.Id)
 	if err != nil {
 		return nil, err
 	}
-	return &pb.GetAccountByIDResponse{
-		Account: &pb.Account{
-			Id:   a.ID,
-			Name: a.Name,
-		},
-	}, nil
+	return &pb.GetAccountByIDResponse{Account: accountToProto(*a)}, nil
 }
 
 func (s *grpcServer) GetAccounts(ctx context.Context, r *pb.GetAccountsRequest) (*pb.GetAccountsResponse, error) {
@@ -67,13 +161,35 @@ func (s *grpcServer) GetAccounts(ctx context.Context, r *pb.GetAccountsRequest)
 	accounts := []*pb.Account{}
 
 	for _, p := range res {
-		accounts = append(accounts, &pb.Account{
-			Id:   p.ID,
-			Name: p.Name,
-		})
+		accounts = append(accounts, accountToProto(p))
 	}
 
 	return &pb.GetAccountsResponse{
 		Accounts: accounts,
 	}, nil
 }
+
+func accountToProto(a Account) *pb.Account {
+	ap := &pb.Account{
+		Id:     a.ID,
+		Name:   a.Name,
+		Email:  a.Email,
+		Phone:  a.Phone,
+		Status: pb.AccountStatus(a.Status),
+	}
+	for _, role := range a.Roles {
+		ap.Roles = append(ap.Roles, string(role))
+	}
+	ap.CreatedAt, _ = a.CreatedAt.MarshalBinary()
+	ap.UpdatedAt, _ = a.UpdatedAt.MarshalBinary()
+	return ap
+}
+
+func tokensToProto(t Tokens) *pb.Tokens {
+	tp := &pb.Tokens{
+		AccessToken:  t.AccessToken,
+		RefreshToken: t.RefreshToken,
+	}
+	tp.ExpiresAt, _ = t.ExpiresAt.MarshalBinary()
+	return tp
+}
@@ -2,33 +2,165 @@ package account
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/rajan-marasini/ecom-microservice/auth"
+	"github.com/rajan-marasini/ecom-microservice/errs"
+	"github.com/rajan-marasini/ecom-microservice/validation"
 	"github.com/segmentio/ksuid"
+	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	// maxNameLength matches the accounts.name VARCHAR(24) column.
+	maxNameLength = 24
+
+	minPasswordLength = 8
+	// maxPasswordBytes is the most bcrypt hashes; longer passwords would be
+	// silently truncated.
+	maxPasswordBytes = 72
+)
+
+// dummyHash is compared against when no account matches a login, so the
+// response time doesn't reveal which emails are registered.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
 type Service interface {
-	PostAccount(ctx context.Context, name string) (*Account, error)
+	PostAccount(ctx context.Context, name, email, phone, password string) (*Account, error)
+	Login(ctx context.Context, email, password string) (*Account, *Tokens, error)
+	RefreshToken(ctx context.Context, refreshToken string) (*Account, *Tokens, error)
+	VerifyToken(ctx context.Context, accessToken string) (*Account, error)
+	UpdateAccount(ctx context.Context, id string, update AccountUpdate) (*Account, error)
+	// ChangePassword sets the password of an account. currentPassword, when
+	// not nil, must match the account's password; nil resets it, which only
+	// admins should be allowed to do.
+	ChangePassword(ctx context.Context, id string, currentPassword *string, newPassword string) (*Account, error)
+	DeleteAccount(ctx context.Context, id string) (*Account, error)
 	GetAccountByID(ctx context.Context, id string) (*Account, error)
 	GetAccounts(ctx context.Context, skip uint64, take uint64) ([]Account, error)
 }
 
 type Account struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Phone     string    `json:"phone"`
+	Status    Status    `json:"status"`
+	Roles     []Role    `json:"roles"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	// PasswordHash is the bcrypt hash of the password, or empty for accounts
+	// that cannot log in.
+	PasswordHash []byte `json:"-"`
+}
+
+// HasRole reports whether the account holds role. Admins hold every role.
+func (a Account) HasRole(role Role) bool {
+	return slices.Contains(a.Roles, role) || slices.Contains(a.Roles, RoleAdmin)
+}
+
+// Role grants access beyond an account's own data. Every account is a
+// customer; merchants manage the catalog; admins may act on any account.
+type Role string
+
+const (
+	RoleCustomer Role = auth.RoleCustomer
+	RoleMerchant Role = auth.RoleMerchant
+	RoleAdmin    Role = auth.RoleAdmin
+)
+
+var roles = []Role{RoleCustomer, RoleMerchant, RoleAdmin}
+
+// Status is the lifecycle state of an account. Closed is final. Values match
+// pb.AccountStatus.
+type Status int
+
+const (
+	StatusActive Status = iota
+	StatusSuspended
+	StatusClosed
+)
+
+var statusNames = map[Status]string{
+	StatusActive:    "active",
+	StatusSuspended: "suspended",
+	StatusClosed:    "closed",
+}
+
+func (s Status) String() string {
+	return statusNames[s]
+}
+
+func parseStatus(name string) (Status, error) {
+	for s, n := range statusNames {
+		if n == name {
+			return s, nil
+		}
+	}
+	return 0, errs.New(errs.Internal, "unknown account status %q", name)
+}
+
+// AccountUpdate lists the fields to change in UpdateAccount. Nil fields are
+// left as they are. Only admins should be allowed to change Roles.
+type AccountUpdate struct {
+	Name   *string
+	Email  *string
+	Phone  *string
+	Status *Status
+	Roles  *[]Role
 }
 
 type accountService struct {
 	repository Repository
+	tokens     *TokenIssuer
 }
 
-func NewService(r Repository) Service {
-	return &accountService{r}
+func NewService(r Repository, tokens *TokenIssuer) Service {
+	return &accountService{r, tokens}
 }
 
-func (s *accountService) PostAccount(ctx context.Context, name string) (*Account, error) {
+// PostAccount creates an active account. Without a password the account
+// exists but cannot log in.
+func (s *accountService) PostAccount(ctx context.Context, name, email, phone, password string) (*Account, error) {
+	email, phone = normalizeEmail(email), normalizePhone(phone)
+
+	checks := []validation.Check{
+		validation.Field("name", name, validation.NotBlank, validation.MaxLength(maxNameLength)),
+		validation.Field("email", email, validation.NotBlank, validation.Email),
+	}
+	if phone != "" {
+		checks = append(checks, validation.Field("phone", phone, validation.Phone))
+	}
+	if password != "" {
+		checks = append(checks, validation.Field("password", password, validation.MinLength(minPasswordLength), maxBytes(maxPasswordBytes)))
+	}
+	if err := validation.Validate(checks...); err != nil {
+		return nil, err
+	}
+
+	var passwordHash []byte
+	if password != "" {
+		var err error
+		if passwordHash, err = bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now().UTC()
 	a := &Account{
-		Name: name,
-		ID:   ksuid.New().String(),
+		Name:         name,
+		ID:           ksuid.New().String(),
+		Email:        email,
+		Phone:        phone,
+		Status:       StatusActive,
+		Roles:        []Role{RoleCustomer},
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		PasswordHash: passwordHash,
 	}
 	if err := s.repository.PutAccount(ctx, *a); err != nil {
 		return nil, err
@@ -36,6 +168,195 @@ func (s *accountService) PostAccount(ctx context.Context, name string) (*Account
 	return a, nil
 }
 
+// Login checks email and password and issues tokens. It reports a wrong
+// email and a wrong password alike.
+func (s *accountService) Login(ctx context.Context, email, password string) (*Account, *Tokens, error) {
+	a, err := s.repository.GetAccountByEmail(ctx, normalizeEmail(email))
+	if err != nil && !errors.Is(err, errs.ErrNotFound) {
+		return nil, nil, err
+	}
+
+	hash := dummyHash
+	if a != nil && len(a.PasswordHash) != 0 {
+		hash = a.PasswordHash
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(password)) != nil || a == nil || len(a.PasswordHash) == 0 {
+		return nil, nil, errs.New(errs.Unauthenticated, "invalid email or password")
+	}
+
+	return s.issueTokens(a)
+}
+
+func (s *accountService) RefreshToken(ctx context.Context, refreshToken string) (*Account, *Tokens, error) {
+	id, err := s.tokens.Verify(refreshToken, refreshTokenType)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	a, err := s.activeAccount(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return s.issueTokens(a)
+}
+
+// VerifyToken returns the account an access token was issued for, as long as
+// it is still active.
+func (s *accountService) VerifyToken(ctx context.Context, accessToken string) (*Account, error) {
+	id, err := s.tokens.Verify(accessToken, accessTokenType)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.activeAccount(ctx, id)
+}
+
+func (s *accountService) issueTokens(a *Account) (*Account, *Tokens, error) {
+	if a.Status != StatusActive {
+		return nil, nil, errs.New(errs.FailedPrecondition, "account %s is %s", a.ID, a.Status)
+	}
+
+	tokens, err := s.tokens.Issue(a.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return a, tokens, nil
+}
+
+// activeAccount loads the account a token was issued for. Tokens of deleted
+// or inactive accounts are rejected.
+func (s *accountService) activeAccount(ctx context.Context, id string) (*Account, error) {
+	a, err := s.repository.GetAccountById(ctx, id)
+	if errors.Is(err, errs.ErrNotFound) {
+		return nil, errs.New(errs.Unauthenticated, "invalid token")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if a.Status != StatusActive {
+		return nil, errs.New(errs.Unauthenticated, "account %s is %s", a.ID, a.Status)
+	}
+
+	return a, nil
+}
+
+func (s *accountService) UpdateAccount(ctx context.Context, id string, update AccountUpdate) (*Account, error) {
+	checks := []validation.Check{}
+	if update.Name != nil {
+		checks = append(checks, validation.Field("name", *update.Name, validation.NotBlank, validation.MaxLength(maxNameLength)))
+	}
+	if update.Email != nil {
+		email := normalizeEmail(*update.Email)
+		update.Email = &email
+		checks = append(checks, validation.Field("email", email, validation.NotBlank, validation.Email))
+	}
+	if update.Phone != nil {
+		phone := normalizePhone(*update.Phone)
+		update.Phone = &phone
+		if phone != "" {
+			checks = append(checks, validation.Field("phone", phone, validation.Phone))
+		}
+	}
+	if update.Status != nil {
+		checks = append(checks, validation.Field("status", *update.Status, func(s Status) string {
+			if s != StatusActive && s != StatusSuspended {
+				return "must be active or suspended; use DeleteAccount to close an account"
+			}
+			return ""
+		}))
+	}
+	if update.Roles != nil {
+		checks = append(checks, validation.Field("roles", *update.Roles, knownRoles))
+	}
+	if err := validation.Validate(checks...); err != nil {
+		return nil, err
+	}
+
+	a, err := s.repository.GetAccountById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if a.Status == StatusClosed {
+		return nil, errs.New(errs.FailedPrecondition, "account %s is closed", id)
+	}
+
+	if update.Name != nil {
+		a.Name = *update.Name
+	}
+	if update.Email != nil {
+		a.Email = *update.Email
+	}
+	if update.Phone != nil {
+		a.Phone = *update.Phone
+	}
+	if update.Status != nil {
+		a.Status = *update.Status
+	}
+	if update.Roles != nil {
+		a.Roles = normalizeRoles(*update.Roles)
+	}
+	a.UpdatedAt = time.Now().UTC()
+
+	if err := s.repository.UpdateAccount(ctx, *a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (s *accountService) ChangePassword(ctx context.Context, id string, currentPassword *string, newPassword string) (*Account, error) {
+	if err := validation.Validate(
+		validation.Field("newPassword", newPassword, validation.MinLength(minPasswordLength), maxBytes(maxPasswordBytes)),
+	); err != nil {
+		return nil, err
+	}
+
+	a, err := s.repository.GetAccountById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if a.Status == StatusClosed {
+		return nil, errs.New(errs.FailedPrecondition, "account %s is closed", id)
+	}
+	if currentPassword != nil && (len(a.PasswordHash) == 0 || bcrypt.CompareHashAndPassword(a.PasswordHash, []byte(*currentPassword)) != nil) {
+		return nil, errs.Invalid("invalid password change", errs.Violation{
+			Field:       "currentPassword",
+			Description: "does not match the account's password",
+		})
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	a.PasswordHash, a.UpdatedAt = hash, time.Now().UTC()
+
+	if err := s.repository.UpdatePasswordHash(ctx, a.ID, a.PasswordHash, a.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// DeleteAccount closes the account. The record is kept so existing orders
+// still refer to a valid account. Closing a closed account is a no-op.
+func (s *accountService) DeleteAccount(ctx context.Context, id string) (*Account, error) {
+	a, err := s.repository.GetAccountById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if a.Status == StatusClosed {
+		return a, nil
+	}
+
+	a.Status = StatusClosed
+	a.UpdatedAt = time.Now().UTC()
+	if err := s.repository.UpdateAccount(ctx, *a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
 func (s *accountService) GetAccountByID(ctx context.Context, id string) (*Account, error) {
 	return s.repository.GetAccountById(ctx, id)
 }
@@ -47,3 +368,51 @@ func (s *accountService) GetAccounts(ctx context.Context, skip uint64, take uint
 
 	return s.repository.ListAccounts(ctx, skip, take)
 }
+
+func knownRoles(assigned []Role) string {
+	for _, role := range assigned {
+		if !slices.Contains(roles, role) {
+			return fmt.Sprintf("unknown role %q", role)
+		}
+	}
+	return ""
+}
+
+// normalizeRoles orders and deduplicates roles and makes sure the account
+// stays a customer.
+func normalizeRoles(assigned []Role) []Role {
+	normalized := []Role{}
+	for _, role := range roles {
+		if role == RoleCustomer || slices.Contains(assigned, role) {
+			normalized = append(normalized, role)
+		}
+	}
+	return normalized
+}
+
+// maxBytes limits the encoded length of a string rather than its characters.
+func maxBytes(n int) validation.Rule[string] {
+	return func(value string) string {
+		if len(value) > n {
+			return fmt.Sprintf("must be at most %d bytes", n)
+		}
+		return ""
+	}
+}
+
+// normalizeEmail lowercases the whole address so lookups and the uniqueness
+// check are case insensitive.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// normalizePhone drops the spaces and punctuation people type in phone
+// numbers, keeping digits and a leading +.
+func normalizePhone(phone string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(" -().", r) {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(phone))
+}
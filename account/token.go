@@ -0,0 +1,98 @@
+package account
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rajan-marasini/ecom-microservice/errs"
+)
+
+const (
+	tokenIssuer = "account"
+
+	accessTokenType  = "access"
+	refreshTokenType = "refresh"
+)
+
+// Tokens is an access token for calling the API and a longer lived refresh
+// token for obtaining the next pair without the password.
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time
+}
+
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	Type string `json:"typ"`
+}
+
+// TokenIssuer signs and verifies HS256 JWTs whose subject is an account ID.
+type TokenIssuer struct {
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+func NewTokenIssuer(secret []byte, accessTTL, refreshTTL time.Duration) *TokenIssuer {
+	return &TokenIssuer{secret, accessTTL, refreshTTL}
+}
+
+func (t *TokenIssuer) Issue(accountID string) (*Tokens, error) {
+	now := time.Now().UTC()
+
+	access, err := t.sign(accountID, accessTokenType, now, t.accessTTL)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := t.sign(accountID, refreshTokenType, now, t.refreshTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tokens{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		ExpiresAt:    now.Add(t.accessTTL),
+	}, nil
+}
+
+func (t *TokenIssuer) sign(accountID, typ string, now time.Time, ttl time.Duration) (string, error) {
+	claims := tokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    tokenIssuer,
+			Subject:   accountID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+		Type: typ,
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(t.secret)
+}
+
+// Verify checks token's signature, expiry and type and returns the account ID
+// it was issued for.
+func (t *TokenIssuer) Verify(token, typ string) (string, error) {
+	claims := &tokenClaims{}
+
+	_, err := jwt.ParseWithClaims(
+		token,
+		claims,
+		func(*jwt.Token) (any, error) { return t.secret, nil },
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(tokenIssuer),
+		jwt.WithExpirationRequired(),
+	)
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return "", errs.New(errs.Unauthenticated, "token expired")
+	case err != nil:
+		return "", errs.Wrap(err, errs.Unauthenticated, "invalid token")
+	case claims.Type != typ || claims.Subject == "":
+		return "", errs.New(errs.Unauthenticated, "invalid token")
+	}
+
+	return claims.Subject, nil
+}
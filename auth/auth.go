@@ -0,0 +1,86 @@
+// Package auth carries the caller's identity from the gateway to the services
+// as gRPC metadata and checks the roles a method requires.
+package auth
+
+import (
+	"context"
+	"slices"
+
+	"github.com/rajan-marasini/ecom-microservice/errs"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Roles an account can hold. Every account is a customer; merchants manage
+// the catalog; admins may do anything.
+const (
+	RoleCustomer = "customer"
+	RoleMerchant = "merchant"
+	RoleAdmin    = "admin"
+)
+
+const (
+	accountIDKey = "x-account-id"
+	rolesKey     = "x-account-roles"
+)
+
+// Identity is the authenticated caller of a request.
+type Identity struct {
+	AccountID string
+	Roles     []string
+}
+
+// HasRole reports whether the identity holds role. Admins hold every role.
+func (id Identity) HasRole(role string) bool {
+	return slices.Contains(id.Roles, role) || slices.Contains(id.Roles, RoleAdmin)
+}
+
+// NewOutgoingContext attaches id to the metadata of gRPC calls made with the
+// returned context.
+func NewOutgoingContext(ctx context.Context, id Identity) context.Context {
+	kv := []string{accountIDKey, id.AccountID}
+	for _, role := range id.Roles {
+		kv = append(kv, rolesKey, role)
+	}
+	return metadata.AppendToOutgoingContext(ctx, kv...)
+}
+
+// FromIncomingContext returns the identity forwarded with a gRPC call. The
+// metadata is trusted as is, so services must only be reachable through the
+// gateway.
+func FromIncomingContext(ctx context.Context) (Identity, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return Identity{}, false
+	}
+	ids := md.Get(accountIDKey)
+	if len(ids) == 0 || ids[0] == "" {
+		return Identity{}, false
+	}
+	return Identity{AccountID: ids[0], Roles: md.Get(rolesKey)}, true
+}
+
+// Require checks that ctx carries an identity holding role.
+func Require(ctx context.Context, role string) error {
+	id, ok := FromIncomingContext(ctx)
+	if !ok {
+		return errs.New(errs.Unauthenticated, "authentication required")
+	}
+	if !id.HasRole(role) {
+		return errs.New(errs.PermissionDenied, "requires role %s", role)
+	}
+	return nil
+}
+
+// UnaryServerInterceptor rejects calls to the methods in roles unless the
+// caller holds the role listed for the method. Other methods are open.
+func UnaryServerInterceptor(roles map[string]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if role, ok := roles[info.FullMethod]; ok {
+			if err := Require(ctx, role); err != nil {
+				return nil, err
+			}
+		}
+		return handler(ctx, req)
+	}
+}
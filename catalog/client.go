@@ -4,7 +4,12 @@ import (
 	"context"
 
 	"github.com/rajan-marasini/ecom-microservice/catalog/pb"
+	"github.com/rajan-marasini/ecom-microservice/errs"
+	"github.com/rajan-marasini/ecom-microservice/grpcclient"
+	"github.com/rajan-marasini/ecom-microservice/money"
 	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 type Client struct {
@@ -12,8 +17,11 @@ type Client struct {
 	service pb.CatalogServiceClient
 }
 
-func NewClient(url string) (*Client, error) {
-	conn, err := grpc.NewClient(url, grpc.WithInsecure())
+// NewClient connects to the service at url, which may list several
+// addresses; see grpcclient.Dial. Reads are retried while the service is
+// unavailable.
+func NewClient(url string, opts grpcclient.Options) (*Client, error) {
+	conn, err := grpcclient.Dial(url, pb.CatalogService_ServiceDesc.ServiceName, opts, "GetProduct", "GetProducts")
 	if err != nil {
 		return nil, err
 	}
@@ -26,22 +34,68 @@ func (c *Client) Close() {
 	c.conn.Close()
 }
 
-func (c *Client) PostProduct(ctx context.Context, name, description string, price float64) (*Product, error) {
+// Health reports an error unless the catalog service is serving.
+func (c *Client) Health(ctx context.Context) error {
+	res, err := healthpb.NewHealthClient(c.conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		return errs.FromGRPC(err)
+	}
+	if res.Status != healthpb.HealthCheckResponse_SERVING {
+		return errs.New(errs.Unavailable, "catalog service is %s", res.Status)
+	}
+	return nil
+}
+
+func (c *Client) PostProduct(ctx context.Context, name, description string, price money.Money) (*Product, error) {
 	res, err := c.service.PostProduct(ctx, &pb.PostProductRequest{
 		Name:        name,
 		Description: description,
-		Price:       price,
+		Price:       money.ToProto(price),
 	})
 	if err != nil {
-		return nil, err
+		return nil, errs.FromGRPC(err)
 	}
 
-	return &Product{
-		ID:          res.Product.Id,
-		Name:        res.Product.Name,
-		Description: res.Product.Description,
-		Price:       res.Product.Price,
-	}, nil
+	p := productFromProto(res.Product)
+	return &p, nil
+}
+
+// UpdateProduct changes the fields of update that are not nil.
+func (c *Client) UpdateProduct(ctx context.Context, id string, update ProductUpdate) (*Product, error) {
+	r := &pb.UpdateProductRequest{
+		Id:         id,
+		UpdateMask: &fieldmaskpb.FieldMask{},
+	}
+	if update.Name != nil {
+		r.Name = *update.Name
+		r.UpdateMask.Paths = append(r.UpdateMask.Paths, "name")
+	}
+	if update.Description != nil {
+		r.Description = *update.Description
+		r.UpdateMask.Paths = append(r.UpdateMask.Paths, "description")
+	}
+	if update.Price != nil {
+		r.Price = money.ToProto(*update.Price)
+		r.UpdateMask.Paths = append(r.UpdateMask.Paths, "price")
+	}
+
+	res, err := c.service.UpdateProduct(ctx, r)
+	if err != nil {
+		return nil, errs.FromGRPC(err)
+	}
+
+	p := productFromProto(res.Product)
+	return &p, nil
+}
+
+func (c *Client) DeleteProduct(ctx context.Context, id string) (*Product, error) {
+	res, err := c.service.DeleteProduct(ctx, &pb.DeleteProductRequest{Id: id})
+	if err != nil {
+		return nil, errs.FromGRPC(err)
+	}
+
+	p := productFromProto(res.Product)
+	return &p, nil
 }
 
 func (c *Client) GetProduct(ctx context.Context, id string) (*Product, error) {
@@ -52,19 +106,14 @@ func (c *Client) GetProduct(ctx context.Context, id string) (*Product, error) {
 		},
 	)
 	if err != nil {
-		return nil, err
+		return nil, errs.FromGRPC(err)
 	}
 
-	return &Product{
-		ID:          res.Product.Id,
-		Name:        res.Product.Name,
-		Description: res.Product.Description,
-		Price:       res.Product.Price,
-	}, nil
-
+	p := productFromProto(res.Product)
+	return &p, nil
 }
 
-func (c *Client) GetProducts(ctx context.Context, skip, take uint64, ids []string, query string) ([]Product, error) {
+func (c *Client) GetProducts(ctx context.Context, skip, take uint64, ids []string, query string, sort ProductSort) ([]Product, error) {
 	res, err := c.service.GetProducts(
 		ctx,
 		&pb.GetProductsRequest{
@@ -72,22 +121,28 @@ func (c *Client) GetProducts(ctx context.Context, skip, take uint64, ids []strin
 			Skip:  skip,
 			Take:  take,
 			Query: query,
+			Sort:  pb.ProductSort(sort),
 		},
 	)
 	if err != nil {
-		return nil, err
+		return nil, errs.FromGRPC(err)
 	}
 
 	products := []Product{}
 
 	for _, p := range res.Products {
-		products = append(products, Product{
-			ID:          p.Id,
-			Name:        p.Name,
-			Description: p.Description,
-			Price:       p.Price,
-		})
+		products = append(products, productFromProto(p))
 	}
 
 	return products, nil
 }
+
+func productFromProto(p *pb.Product) Product {
+	return Product{
+		ID:          p.Id,
+		Name:        p.Name,
+		Description: p.Description,
+		Price:       money.FromProto(p.Price),
+		Archived:    p.Archived,
+	}
+}
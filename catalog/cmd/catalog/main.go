@@ -1,16 +1,35 @@
 package main
 
 import (
+	"context"
 	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/kelseyhightower/envconfig"
 	"github.com/rajan-marasini/ecom-microservice/catalog"
+	"github.com/rajan-marasini/ecom-microservice/logging"
+	"github.com/rajan-marasini/ecom-microservice/metrics"
+	"github.com/rajan-marasini/ecom-microservice/mtls"
+	"github.com/rajan-marasini/ecom-microservice/tracing"
 	"github.com/tinrab/retry"
 )
 
 type Config struct {
-	DatabaseURL string `envconfig:"DATABASE_URL"`
+	DatabaseURL    string `envconfig:"DATABASE_URL"`
+	StorageBackend string `envconfig:"STORAGE_BACKEND" default:"elastic"`
+	// TLS enables mutual TLS with TLS_CERT_FILE, TLS_KEY_FILE and TLS_CA_FILE.
+	TLS mtls.Config `envconfig:"TLS"`
+	// ShutdownTimeout bounds how long in-flight requests may run after SIGTERM.
+	ShutdownTimeout time.Duration `envconfig:"SHUTDOWN_TIMEOUT" default:"15s"`
+	// MetricsPort serves Prometheus metrics at /metrics.
+	MetricsPort int `envconfig:"METRICS_PORT" default:"9090"`
+	// Log sets the lowest level logged with LOG_LEVEL (debug, info, warn or
+	// error) and the format with LOG_FORMAT (json or text).
+	Log logging.Config `envconfig:"LOG"`
 }
 
 func main() {
@@ -21,17 +40,72 @@ func main() {
 		log.Fatal(err)
 	}
 
+	if err := logging.Init(cfg.Log, "catalog"); err != nil {
+		log.Fatal(err)
+	}
+
+	creds, err := mtls.Load(cfg.TLS)
+	if err != nil {
+		logging.Fatal("loading TLS credentials failed", "error", err)
+	}
+
+	shutdownTracing, err := tracing.Init(context.Background(), "catalog")
+	if err != nil {
+		logging.Fatal("initializing tracing failed", "error", err)
+	}
+
 	var r catalog.Repository
-	retry.ForeverSleep(2*time.Second, func(_ int) (err error) {
-		r, err = catalog.NewElasticRepository(cfg.DatabaseURL)
-		if err != nil {
-			log.Println(err)
-		}
-		return
-	})
-	defer r.Close()
-	log.Println("listening on port 8080")
+	switch cfg.StorageBackend {
+	case "memory":
+		r = catalog.NewMemoryRepository()
+	case "elastic":
+		retry.ForeverSleep(2*time.Second, func(_ int) (err error) {
+			r, err = catalog.NewTypelessElasticRepository(cfg.DatabaseURL)
+			if err != nil {
+				slog.Error("connecting to storage failed, retrying", "error", err)
+			}
+			return
+		})
+	case "elastic5":
+		retry.ForeverSleep(2*time.Second, func(_ int) (err error) {
+			r, err = catalog.NewElasticRepository(cfg.DatabaseURL)
+			if err != nil {
+				slog.Error("connecting to storage failed, retrying", "error", err)
+			}
+			return
+		})
+	default:
+		logging.Fatal("unknown storage backend", "backend", cfg.StorageBackend)
+	}
+
 	s := catalog.NewService(r)
-	log.Fatal(catalog.ListenGRPC(s, 8080))
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		slog.Error("metrics server stopped", "error", metrics.ListenAndServe(cfg.MetricsPort))
+	}()
+
+	srv, err := catalog.ListenGRPC(ctx, s, 8080, creds, r.Ping)
+	if err != nil {
+		r.Close()
+		logging.Fatal("starting gRPC server failed", "error", err)
+	}
+	slog.Info("listening", "port", 8080)
+
+	select {
+	case <-ctx.Done():
+		slog.Info("shutting down")
+	case <-srv.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	err = srv.Shutdown(shutdownCtx)
+	r.Close()
+	shutdownTracing(shutdownCtx)
+	if err != nil {
+		logging.Fatal("shutdown failed", "error", err)
+	}
 }
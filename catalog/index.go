@@ -0,0 +1,164 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+
+	elastic "gopkg.in/olivere/elastic.v5"
+)
+
+// Products are read and written through the indexAlias alias, which points at
+// one versioned index such as catalog_v1. Bump indexVersion whenever
+// indexSettings or productMapping change: on startup the service creates the
+// new index, copies the documents over and moves the alias in one atomic
+// step, so readers never see a missing or half filled index. Previous
+// versions are kept for rollback and can be deleted by hand.
+//
+// A legacy concrete index named like indexAlias, left by releases before
+// aliases, is deleted by the same request that adds the alias, so the name
+// never goes missing. Writes made to it while its documents are being copied
+// are lost, so run this one-time migration while the catalog takes no writes.
+const (
+	indexAlias   = "catalog"
+	indexVersion = 2
+)
+
+func indexName(version int) string {
+	return fmt.Sprintf("%s_v%d", indexAlias, version)
+}
+
+var indexSettings = map[string]any{
+	"analysis": map[string]any{
+		"filter": map[string]any{
+			"autocomplete": map[string]any{
+				"type":     "edge_ngram",
+				"min_gram": 2,
+				"max_gram": 20,
+			},
+		},
+		"analyzer": map[string]any{
+			"autocomplete": map[string]any{
+				"type":      "custom",
+				"tokenizer": "standard",
+				"filter":    []string{"lowercase", "autocomplete"},
+			},
+		},
+	},
+}
+
+// productMapping describes productDocument. Prices are kept exact in _source;
+// the scaled_float only serves sorting and range queries, and its scaling
+// factor covers currencies with three minor unit digits.
+var productMapping = map[string]any{
+	"dynamic": "strict",
+	"properties": map[string]any{
+		"id": map[string]any{"type": "keyword"},
+		"name": map[string]any{
+			"type":     "text",
+			"analyzer": "english",
+			"fields": map[string]any{
+				"keyword": map[string]any{"type": "keyword", "ignore_above": 256},
+				"autocomplete": map[string]any{
+					"type":            "text",
+					"analyzer":        "autocomplete",
+					"search_analyzer": "standard",
+				},
+			},
+		},
+		"description": map[string]any{"type": "text", "analyzer": "english"},
+		"price":       map[string]any{"type": "scaled_float", "scaling_factor": 1000},
+		"currency":    map[string]any{"type": "keyword"},
+		"archived":    map[string]any{"type": "boolean"},
+	},
+}
+
+// copyIDScript fills the id field of documents indexed before it existed.
+const copyIDScript = "ctx._source.id = ctx._id"
+
+// ensureIndex creates the current index version and points indexAlias at it,
+// reindexing from whatever the alias pointed at before. A legacy concrete
+// index named like the alias is replaced by the alias, in the same request,
+// once its documents are copied.
+func (r *elasticRepository) ensureIndex(ctx context.Context) error {
+	target := indexName(indexVersion)
+
+	exists, err := r.client.IndexExists(target).Do(ctx)
+	if err != nil {
+		return elasticError(err)
+	}
+	if !exists {
+		_, err := r.client.CreateIndex(target).
+			BodyJson(map[string]any{
+				"settings": indexSettings,
+				"mappings": map[string]any{"product": productMapping},
+			}).
+			Do(ctx)
+		if err != nil {
+			return elasticError(err)
+		}
+	}
+
+	sources, legacy, err := r.aliasedIndices(ctx)
+	if err != nil {
+		return err
+	}
+	if len(sources) == 1 && sources[0] == target {
+		return nil
+	}
+
+	for _, source := range sources {
+		if source == target {
+			continue
+		}
+		_, err := r.client.Reindex().
+			SourceIndex(source).
+			DestinationIndexAndType(target, "product").
+			Script(elastic.NewScriptInline(copyIDScript).Lang("painless")).
+			WaitForCompletion(true).
+			Refresh("true").
+			Do(ctx)
+		if err != nil {
+			return elasticError(err)
+		}
+	}
+
+	alias := r.client.Alias()
+	if legacy {
+		alias = alias.Action(removeIndexAction(indexAlias))
+	}
+	alias = alias.Add(target, indexAlias)
+	for _, source := range sources {
+		if !legacy && source != target {
+			alias = alias.Remove(source, indexAlias)
+		}
+	}
+	_, err = alias.Do(ctx)
+	return elasticError(err)
+}
+
+// removeIndexAction deletes an index as part of an alias request, which lets
+// an alias take over the name of the index atomically.
+type removeIndexAction string
+
+func (a removeIndexAction) Source() (any, error) {
+	return map[string]any{"remove_index": map[string]string{"index": string(a)}}, nil
+}
+
+// aliasedIndices returns the indices indexAlias currently resolves to, and
+// whether it is a concrete index rather than an alias.
+func (r *elasticRepository) aliasedIndices(ctx context.Context) ([]string, bool, error) {
+	exists, err := r.client.IndexExists(indexAlias).Do(ctx)
+	if err != nil || !exists {
+		return nil, false, elasticError(err)
+	}
+
+	res, err := r.client.Aliases().Index(indexAlias).Do(ctx)
+	if err != nil {
+		return nil, false, elasticError(err)
+	}
+	if _, ok := res.Indices[indexAlias]; ok {
+		return []string{indexAlias}, true, nil
+	}
+
+	return res.IndicesByAlias(indexAlias), false, nil
+}
@@ -0,0 +1,59 @@
+package catalog
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+var elasticsearchDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "catalog_elasticsearch_request_seconds",
+	Help:    "Time taken by Elasticsearch requests, by endpoint and HTTP status code.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"endpoint", "code"})
+
+// instrumentedTransport records the latency of Elasticsearch requests. It is
+// wrapped in an otelhttp transport so each request is also a span.
+type instrumentedTransport struct {
+	next http.RoundTripper
+}
+
+func newInstrumentedClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: otelhttp.NewTransport(
+			instrumentedTransport{http.DefaultTransport},
+			otelhttp.WithSpanNameFormatter(func(_ string, req *http.Request) string {
+				return "elasticsearch " + endpoint(req.URL.Path)
+			}),
+		),
+	}
+}
+
+func (t instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	res, err := t.next.RoundTrip(req)
+
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(res.StatusCode)
+	}
+	elasticsearchDuration.WithLabelValues(endpoint(req.URL.Path), code).Observe(time.Since(start).Seconds())
+	return res, err
+}
+
+// endpoint names the API a request path calls, such as _search or _doc,
+// leaving out index names and document IDs.
+func endpoint(path string) string {
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "_") {
+			return segment
+		}
+	}
+	return "index"
+}
@@ -0,0 +1,200 @@
+package catalog
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/rajan-marasini/ecom-microservice/errs"
+)
+
+type memoryRepository struct {
+	mu       sync.RWMutex
+	products map[string]Product
+}
+
+// NewMemoryRepository returns a Repository that keeps products in process
+// memory. Search approximates the Elasticsearch multi_match query over name
+// and description, and is meant for demos and tests.
+func NewMemoryRepository() Repository {
+	return &memoryRepository{
+		products: map[string]Product{},
+	}
+}
+
+func (r *memoryRepository) Close() {
+}
+
+func (r *memoryRepository) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (r *memoryRepository) PutProduct(ctx context.Context, p Product) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.products[p.ID] = p
+
+	return nil
+}
+
+func (r *memoryRepository) UpdateProduct(ctx context.Context, p Product) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.products[p.ID]; !ok {
+		return errs.New(errs.NotFound, "product %s not found", p.ID)
+	}
+	r.products[p.ID] = p
+
+	return nil
+}
+
+func (r *memoryRepository) GetProductByID(ctx context.Context, id string) (*Product, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, ok := r.products[id]
+	if !ok {
+		return nil, errs.New(errs.NotFound, "product %s not found", id)
+	}
+
+	return &p, nil
+}
+
+func (r *memoryRepository) ListProducts(ctx context.Context, skip uint64, take uint64, sort ProductSort) ([]Product, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	products := make([]Product, 0, len(r.products))
+	for _, p := range r.products {
+		if !p.Archived {
+			products = append(products, p)
+		}
+	}
+
+	sortProducts(products, sort)
+
+	return paginate(products, skip, take), nil
+}
+
+func (r *memoryRepository) ListProductsWithIDs(ctx context.Context, ids []string) ([]Product, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	products := []Product{}
+	for _, id := range ids {
+		if p, ok := r.products[id]; ok {
+			products = append(products, p)
+		}
+	}
+
+	return products, nil
+}
+
+func (r *memoryRepository) SearchProducts(ctx context.Context, query string, skip uint64, take uint64, sort ProductSort) ([]Product, error) {
+	terms := tokenize(query)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	type hit struct {
+		product Product
+		score   int
+	}
+
+	hits := []hit{}
+	for _, p := range r.products {
+		if p.Archived {
+			continue
+		}
+		score := max(matchCount(terms, p.Name), matchCount(terms, p.Description))
+		if score > 0 {
+			hits = append(hits, hit{p, score})
+		}
+	}
+
+	compare := productComparer(sort)
+	slices.SortFunc(hits, func(a, b hit) int {
+		if c := cmp.Compare(b.score, a.score); c != 0 {
+			return c
+		}
+		return compare(a.product, b.product)
+	})
+
+	products := make([]Product, 0, len(hits))
+	for _, h := range hits {
+		products = append(products, h.product)
+	}
+
+	return paginate(products, skip, take), nil
+}
+
+// sortProducts orders products the same way elasticSorters does.
+func sortProducts(products []Product, sort ProductSort) {
+	slices.SortFunc(products, productComparer(sort))
+}
+
+func productComparer(sort ProductSort) func(a, b Product) int {
+	return func(a, b Product) int {
+		var c int
+		switch sort {
+		case SortPriceAsc:
+			c = cmp.Compare(a.Price.Amount, b.Price.Amount)
+		case SortPriceDesc:
+			c = cmp.Compare(b.Price.Amount, a.Price.Amount)
+		case SortName:
+			c = strings.Compare(a.Name, b.Name)
+		default:
+			return strings.Compare(b.ID, a.ID)
+		}
+		if c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	}
+}
+
+// tokenize splits text the way the Elasticsearch standard analyzer roughly
+// does: lowercased runs of letters and digits.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// matchCount returns how many distinct query terms occur in field, which
+// stands in for the best_fields score of a multi_match query.
+func matchCount(terms []string, field string) int {
+	tokens := map[string]bool{}
+	for _, t := range tokenize(field) {
+		tokens[t] = true
+	}
+
+	count := 0
+	seen := map[string]bool{}
+	for _, t := range terms {
+		if tokens[t] && !seen[t] {
+			seen[t] = true
+			count++
+		}
+	}
+
+	return count
+}
+
+func paginate(products []Product, skip uint64, take uint64) []Product {
+	if skip >= uint64(len(products)) {
+		return []Product{}
+	}
+	products = products[skip:]
+
+	if take < uint64(len(products)) {
+		products = products[:take]
+	}
+
+	return products
+}
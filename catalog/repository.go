@@ -3,77 +3,142 @@ package catalog
 import (
 	"context"
 	"encoding/json"
-	"errors"
-	"log"
 
+	"github.com/rajan-marasini/ecom-microservice/errs"
+	"github.com/rajan-marasini/ecom-microservice/money"
 	elastic "gopkg.in/olivere/elastic.v5"
 )
 
-var (
-	ErrNotFound = errors.New("entity not found")
-)
-
 type Repository interface {
 	Close()
+	// Ping reports an error when the storage cannot be reached.
+	Ping(ctx context.Context) error
 	PutProduct(ctx context.Context, p Product) error
+	UpdateProduct(ctx context.Context, p Product) error
 	GetProductByID(ctx context.Context, id string) (*Product, error)
-	ListProducts(ctx context.Context, skip uint64, take uint64) ([]Product, error)
+	ListProducts(ctx context.Context, skip uint64, take uint64, sort ProductSort) ([]Product, error)
 	ListProductsWithIDs(ctx context.Context, ids []string) ([]Product, error)
-	SearchProducts(ctx context.Context, query string, skip uint64, take uint64) ([]Product, error)
+	// SearchProducts orders matches by relevance, then by sort.
+	SearchProducts(ctx context.Context, query string, skip uint64, take uint64, sort ProductSort) ([]Product, error)
 }
 
 type elasticRepository struct {
 	client *elastic.Client
 }
 
+// productDocument stores the price as an exact decimal number in major units
+// plus its currency. Documents written before currencies existed are read as
+// DefaultCurrency. The ID is repeated in the document so it can be sorted on.
+// ListProducts and SearchProducts skip archived documents.
 type productDocument struct {
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Price       json.Number `json:"price"`
+	Currency    string      `json:"currency,omitempty"`
+	Archived    bool        `json:"archived"`
+}
+
+func newProductDocument(p Product) productDocument {
+	return productDocument{
+		ID:          p.ID,
+		Name:        p.Name,
+		Description: p.Description,
+		Price:       json.Number(p.Price.Decimal()),
+		Currency:    p.Price.Currency,
+		Archived:    p.Archived,
+	}
+}
+
+func (d productDocument) product(id string) Product {
+	currency := d.Currency
+	if currency == "" {
+		currency = money.DefaultCurrency
+	}
+
+	price, err := money.Parse(d.Price.String(), currency)
+	if err != nil {
+		f, _ := d.Price.Float64()
+		price = money.FromFloat(f, currency)
+	}
+
+	return Product{
+		ID:          id,
+		Name:        d.Name,
+		Description: d.Description,
+		Price:       price,
+		Archived:    d.Archived,
+	}
 }
 
 func NewElasticRepository(url string) (Repository, error) {
 	client, err := elastic.NewClient(
 		elastic.SetURL(url),
 		elastic.SetSniff(false),
+		elastic.SetHttpClient(newInstrumentedClient(0)),
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	return &elasticRepository{client}, nil
+	r := &elasticRepository{client}
+	if err := r.ensureIndex(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return r, nil
 }
 
 func (r *elasticRepository) Close() {
 }
 
+// Ping fails while the cluster is red, when some products can't be read.
+func (r *elasticRepository) Ping(ctx context.Context) error {
+	res, err := r.client.ClusterHealth().Index(indexAlias).Do(ctx)
+	if err != nil {
+		return err
+	}
+	if res.Status == "red" {
+		return errs.New(errs.Unavailable, "elasticsearch cluster is red")
+	}
+	return nil
+}
+
 func (r *elasticRepository) PutProduct(ctx context.Context, p Product) error {
 	_, err := r.client.Index().
-		Index("catalog").
+		Index(indexAlias).
 		Type("product").
 		Id(p.ID).
-		BodyJson(productDocument{
-			Name:        p.Name,
-			Description: p.Description,
-			Price:       p.Price,
-		}).
+		BodyJson(newProductDocument(p)).
 		Do(ctx)
-	return err
+	return elasticError(err)
+}
+
+func (r *elasticRepository) UpdateProduct(ctx context.Context, p Product) error {
+	_, err := r.client.Update().
+		Index(indexAlias).
+		Type("product").
+		Id(p.ID).
+		Doc(newProductDocument(p)).
+		Do(ctx)
+	if elastic.IsNotFound(err) {
+		return errs.New(errs.NotFound, "product %s not found", p.ID)
+	}
+	return elasticError(err)
 }
 
 func (r *elasticRepository) GetProductByID(ctx context.Context, id string) (*Product, error) {
 	res, err := r.client.Get().
-		Index("catalog").
+		Index(indexAlias).
 		Type("product").
 		Id(id).
 		Do(ctx)
 
-	if err != nil {
-		return nil, err
+	if elastic.IsNotFound(err) || (err == nil && !res.Found) {
+		return nil, errs.New(errs.NotFound, "product %s not found", id)
 	}
-
-	if !res.Found {
-		return nil, ErrNotFound
+	if err != nil {
+		return nil, elasticError(err)
 	}
 
 	p := productDocument{}
@@ -81,24 +146,20 @@ func (r *elasticRepository) GetProductByID(ctx context.Context, id string) (*Pro
 		return nil, err
 	}
 
-	return &Product{
-		ID:          id,
-		Name:        p.Name,
-		Description: p.Description,
-		Price:       p.Price,
-	}, nil
+	product := p.product(id)
+	return &product, nil
 }
 
-func (r *elasticRepository) ListProducts(ctx context.Context, skip uint64, take uint64) ([]Product, error) {
+func (r *elasticRepository) ListProducts(ctx context.Context, skip uint64, take uint64, sort ProductSort) ([]Product, error) {
 	res, err := r.client.Search().
-		Index("catalog").
+		Index(indexAlias).
 		Type("product").
-		Query(elastic.NewMatchAllQuery()).
+		Query(visible(elastic.NewMatchAllQuery())).
+		SortBy(elasticSorters(sort)...).
 		From(int(skip)).Size(int(take)).
 		Do(ctx)
 	if err != nil {
-		log.Println(err)
-		return nil, err
+		return nil, elasticError(err)
 	}
 
 	products := []Product{}
@@ -108,22 +169,42 @@ func (r *elasticRepository) ListProducts(ctx context.Context, skip uint64, take
 		if err := json.Unmarshal(*hit.Source, &p); err != nil {
 			return nil, err
 		}
-		products = append(products, Product{
-			ID:          hit.Id,
-			Name:        p.Name,
-			Description: p.Description,
-			Price:       p.Price,
-		})
+		products = append(products, p.product(hit.Id))
 	}
 	return products, err
 }
 
+// visible restricts query to products that are not archived.
+func visible(query elastic.Query) elastic.Query {
+	return elastic.NewBoolQuery().
+		Must(query).
+		MustNot(elastic.NewTermQuery("archived", true))
+}
+
+// elasticSorters translates sort into Elasticsearch sort clauses. Names are
+// sorted on their keyword subfield, and id is the tie breaker since KSUID
+// product IDs sort by creation time.
+func elasticSorters(sort ProductSort) []elastic.Sorter {
+	tieBreaker := elastic.NewFieldSort("id").Asc()
+
+	switch sort {
+	case SortPriceAsc:
+		return []elastic.Sorter{elastic.NewFieldSort("price").Asc(), tieBreaker}
+	case SortPriceDesc:
+		return []elastic.Sorter{elastic.NewFieldSort("price").Desc(), tieBreaker}
+	case SortName:
+		return []elastic.Sorter{elastic.NewFieldSort("name.keyword").Asc(), tieBreaker}
+	default:
+		return []elastic.Sorter{elastic.NewFieldSort("id").Desc()}
+	}
+}
+
 func (r *elasticRepository) ListProductsWithIDs(ctx context.Context, ids []string) ([]Product, error) {
 	items := []*elastic.MultiGetItem{}
 
 	for _, id := range ids {
 		items = append(items, elastic.NewMultiGetItem().
-			Index("catalog").
+			Index(indexAlias).
 			Type("product").
 			Id(id))
 
@@ -134,37 +215,36 @@ func (r *elasticRepository) ListProductsWithIDs(ctx context.Context, ids []strin
 		Do(ctx)
 
 	if err != nil {
-		log.Println(err)
-		return nil, err
+		return nil, elasticError(err)
 	}
 
 	products := []Product{}
 
 	for _, doc := range res.Docs {
+		if !doc.Found || doc.Source == nil {
+			continue
+		}
+
 		p := productDocument{}
 		if err := json.Unmarshal(*doc.Source, &p); err != nil {
 			return nil, err
 		}
-		products = append(products, Product{
-			ID:          doc.Id,
-			Name:        p.Name,
-			Description: p.Description,
-			Price:       p.Price,
-		})
+		products = append(products, p.product(doc.Id))
 	}
 
 	return products, nil
 }
 
-func (r *elasticRepository) SearchProducts(ctx context.Context, query string, skip uint64, take uint64) ([]Product, error) {
+func (r *elasticRepository) SearchProducts(ctx context.Context, query string, skip uint64, take uint64, sort ProductSort) ([]Product, error) {
 	res, err := r.client.Search().
-		Index("catalog").
+		Index(indexAlias).
 		Type("product").
-		Query(elastic.NewMultiMatchQuery(query, "name", "description")).
+		Query(visible(elastic.NewMultiMatchQuery(query, "name^2", "name.autocomplete", "description"))).
+		SortBy(append([]elastic.Sorter{elastic.NewScoreSort()}, elasticSorters(sort)...)...).
 		From(int(skip)).Size(int(take)).
 		Do(ctx)
 	if err != nil {
-		return nil, err
+		return nil, elasticError(err)
 	}
 
 	products := []Product{}
@@ -176,12 +256,16 @@ func (r *elasticRepository) SearchProducts(ctx context.Context, query string, sk
 			return nil, err
 		}
 
-		products = append(products, Product{
-			ID:          hit.Id,
-			Name:        p.Name,
-			Description: p.Description,
-			Price:       p.Price,
-		})
+		products = append(products, p.product(hit.Id))
 	}
 	return products, nil
 }
+
+// elasticError marks connection failures and timeouts as Unavailable so
+// callers can tell them apart from bad requests.
+func elasticError(err error) error {
+	if elastic.IsConnErr(err) || elastic.IsTimeout(err) {
+		return errs.Wrap(err, errs.Unavailable, "catalog storage unavailable")
+	}
+	return err
+}
@@ -1,51 +1,108 @@
-//go:generate protoc --go_out=./pb --go_opt=paths=source_relative --go-grpc_out=./pb --go-grpc_opt=paths=source_relative catalog.proto
+//go:generate protoc -I . -I .. --go_out=./pb --go_opt=paths=source_relative --go-grpc_out=./pb --go-grpc_opt=paths=source_relative catalog.proto
 package catalog
 
 import (
 	"context"
 	"fmt"
-	"log"
-	"net"
 
+	"github.com/rajan-marasini/ecom-microservice/auth"
 	"github.com/rajan-marasini/ecom-microservice/catalog/pb"
+	"github.com/rajan-marasini/ecom-microservice/errs"
+	"github.com/rajan-marasini/ecom-microservice/grpcserver"
+	"github.com/rajan-marasini/ecom-microservice/logging"
+	"github.com/rajan-marasini/ecom-microservice/metrics"
+	"github.com/rajan-marasini/ecom-microservice/money"
+	"github.com/rajan-marasini/ecom-microservice/mtls"
+	"github.com/rajan-marasini/ecom-microservice/tracing"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 )
 
+// methodRoles are the roles required to call catalog methods that change
+// products. Reads are open.
+var methodRoles = map[string]string{
+	pb.CatalogService_PostProduct_FullMethodName:   auth.RoleMerchant,
+	pb.CatalogService_UpdateProduct_FullMethodName: auth.RoleMerchant,
+	pb.CatalogService_DeleteProduct_FullMethodName: auth.RoleMerchant,
+}
+
 type grpcServer struct {
 	pb.UnimplementedCatalogServiceServer
 	service Service
 }
 
-func ListenGRPC(s Service, port int) error {
-	list, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
-	if err != nil {
-		return err
-	}
-
-	serv := grpc.NewServer()
+// ListenGRPC serves s on port. The server reports itself healthy while checks
+// pass.
+func ListenGRPC(ctx context.Context, s Service, port int, creds *mtls.Credentials, checks ...grpcserver.Check) (*grpcserver.Server, error) {
+	serv := grpc.NewServer(
+		creds.ServerOption(),
+		tracing.ServerOption(),
+		grpc.ChainUnaryInterceptor(
+			metrics.UnaryServerInterceptor,
+			errs.UnaryServerInterceptor,
+			logging.UnaryServerInterceptor,
+			auth.UnaryServerInterceptor(methodRoles),
+		),
+	)
 	pb.RegisterCatalogServiceServer(serv, &grpcServer{
 		service: s,
 	})
 	reflection.Register(serv)
-	return serv.Serve(list)
+	grpcserver.RegisterHealth(ctx, serv, pb.CatalogService_ServiceDesc.ServiceName, checks...)
+	return grpcserver.Start(ctx, serv, port)
 }
 
 func (s *grpcServer) PostProduct(ctx context.Context, r *pb.PostProductRequest) (*pb.PostProductResponse, error) {
-	p, err := s.service.PostProduct(ctx, r.Name, r.Description, r.Price)
+	p, err := s.service.PostProduct(ctx, r.Name, r.Description, money.FromProto(r.Price))
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.PostProductResponse{Product: productToProto(*p)}, nil
+}
+
+func (s *grpcServer) UpdateProduct(ctx context.Context, r *pb.UpdateProductRequest) (*pb.UpdateProductResponse, error) {
+	update := ProductUpdate{}
+	violations := []errs.Violation{}
+
+	for _, path := range r.UpdateMask.GetPaths() {
+		switch path {
+		case "name":
+			update.Name = &r.Name
+		case "description":
+			update.Description = &r.Description
+		case "price":
+			price := money.FromProto(r.Price)
+			update.Price = &price
+		default:
+			violations = append(violations, errs.Violation{
+				Field:       "updateMask",
+				Description: fmt.Sprintf("unknown field %q", path),
+			})
+		}
+	}
+	if len(r.UpdateMask.GetPaths()) == 0 {
+		violations = append(violations, errs.Violation{Field: "updateMask", Description: "must not be empty"})
+	}
+	if len(violations) > 0 {
+		return nil, errs.Invalid("invalid update mask", violations...)
+	}
+
+	p, err := s.service.UpdateProduct(ctx, r.Id, update)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.UpdateProductResponse{Product: productToProto(*p)}, nil
+}
+
+func (s *grpcServer) DeleteProduct(ctx context.Context, r *pb.DeleteProductRequest) (*pb.DeleteProductResponse, error) {
+	p, err := s.service.DeleteProduct(ctx, r.Id)
 	if err != nil {
-		log.Println(err)
 		return nil, err
 	}
 
-	return &pb.PostProductResponse{
-		Product: &pb.Product{
-			Id:          p.ID,
-			Name:        p.Name,
-			Description: p.Description,
-			Price:       p.Price,
-		},
-	}, nil
+	return &pb.DeleteProductResponse{Product: productToProto(*p)}, nil
 }
 
 func (s *grpcServer) GetProduct(ctx context.Context, r *pb.GetProductRequest) (*pb.GetProductResponse, error) {
@@ -54,14 +111,7 @@ func (s *grpcServer) GetProduct(ctx context.Context, r *pb.GetProductRequest) (*
 		return nil, err
 	}
 
-	return &pb.GetProductResponse{
-		Product: &pb.Product{
-			Id:          p.ID,
-			Name:        p.Name,
-			Price:       p.Price,
-			Description: p.Description,
-		},
-	}, nil
+	return &pb.GetProductResponse{Product: productToProto(*p)}, nil
 }
 
 func (s *grpcServer) GetProducts(ctx context.Context, r *pb.GetProductsRequest) (*pb.GetProductsResponse, error) {
@@ -69,27 +119,33 @@ func (s *grpcServer) GetProducts(ctx context.Context, r *pb.GetProductsRequest)
 	var err error
 
 	if r.Query != "" {
-		res, err = s.service.SearchProducts(ctx, r.Query, r.Skip, r.Take)
+		res, err = s.service.SearchProducts(ctx, r.Query, r.Skip, r.Take, ProductSort(r.Sort))
 
 	} else if len(r.Ids) != 0 {
 		res, err = s.service.GetProductByID(ctx, r.Ids)
+	} else {
+		res, err = s.service.GetProducts(ctx, r.Skip, r.Take, ProductSort(r.Sort))
 	}
 
 	if err != nil {
-		log.Println(err)
 		return nil, err
 	}
 
 	products := []*pb.Product{}
 
 	for _, p := range res {
-		products = append(products, &pb.Product{
-			Id:          p.ID,
-			Name:        p.Name,
-			Description: p.Description,
-			Price:       p.Price,
-		})
+		products = append(products, productToProto(p))
 	}
 
 	return &pb.GetProductsResponse{Products: products}, nil
 }
+
+func productToProto(p Product) *pb.Product {
+	return &pb.Product{
+		Id:          p.ID,
+		Name:        p.Name,
+		Description: p.Description,
+		Price:       money.ToProto(p.Price),
+		Archived:    p.Archived,
+	}
+}
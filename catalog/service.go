@@ -3,24 +3,50 @@ package catalog
 import (
 	"context"
 
+	"github.com/rajan-marasini/ecom-microservice/errs"
+	"github.com/rajan-marasini/ecom-microservice/money"
+	"github.com/rajan-marasini/ecom-microservice/validation"
 	"github.com/segmentio/ksuid"
 )
 
 type Service interface {
-	PostProduct(ctx context.Context, name, description string, price float64) (*Product, error)
+	PostProduct(ctx context.Context, name, description string, price money.Money) (*Product, error)
+	UpdateProduct(ctx context.Context, id string, update ProductUpdate) (*Product, error)
+	DeleteProduct(ctx context.Context, id string) (*Product, error)
 	GetProduct(ctx context.Context, id string) (*Product, error)
-	GetProducts(ctx context.Context, skip uint64, take uint64) ([]Product, error)
+	GetProducts(ctx context.Context, skip uint64, take uint64, sort ProductSort) ([]Product, error)
 	GetProductByID(ctx context.Context, ids []string) ([]Product, error)
-	SearchProducts(ctx context.Context, query string, skip uint64, take uint64) ([]Product, error)
+	SearchProducts(ctx context.Context, query string, skip uint64, take uint64, sort ProductSort) ([]Product, error)
 }
 
 type Product struct {
-	ID          string  `json:"id"`
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Price       money.Money `json:"price"`
+	Archived    bool        `json:"archived"`
 }
 
+// ProductUpdate lists the fields to change in UpdateProduct. Nil fields are
+// left as they are.
+type ProductUpdate struct {
+	Name        *string
+	Description *string
+	Price       *money.Money
+}
+
+// ProductSort selects the order in which products are listed. Product IDs are
+// KSUIDs, so sorting by ID is sorting by creation time; ID also breaks ties.
+// Values match pb.ProductSort.
+type ProductSort int
+
+const (
+	SortNewest ProductSort = iota
+	SortPriceAsc
+	SortPriceDesc
+	SortName
+)
+
 type catalogService struct {
 	repo Repository
 }
@@ -29,7 +55,14 @@ func NewService(r Repository) Service {
 	return &catalogService{r}
 }
 
-func (r *catalogService) PostProduct(ctx context.Context, name, description string, price float64) (*Product, error) {
+func (r *catalogService) PostProduct(ctx context.Context, name, description string, price money.Money) (*Product, error) {
+	if err := validation.Validate(
+		validation.Field("name", name, validation.NotBlank),
+		validation.Field("price", price, money.Validate),
+	); err != nil {
+		return nil, err
+	}
+
 	p := &Product{
 		Name:        name,
 		Description: description,
@@ -43,25 +76,80 @@ func (r *catalogService) PostProduct(ctx context.Context, name, description stri
 	return p, nil
 }
 
+func (r *catalogService) UpdateProduct(ctx context.Context, id string, update ProductUpdate) (*Product, error) {
+	checks := []validation.Check{}
+	if update.Name != nil {
+		checks = append(checks, validation.Field("name", *update.Name, validation.NotBlank))
+	}
+	if update.Price != nil {
+		checks = append(checks, validation.Field("price", *update.Price, money.Validate))
+	}
+	if err := validation.Validate(checks...); err != nil {
+		return nil, err
+	}
+
+	p, err := r.repo.GetProductByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if p.Archived {
+		return nil, errs.New(errs.FailedPrecondition, "product %s is archived", id)
+	}
+
+	if update.Name != nil {
+		p.Name = *update.Name
+	}
+	if update.Description != nil {
+		p.Description = *update.Description
+	}
+	if update.Price != nil {
+		p.Price = *update.Price
+	}
+	if err := r.repo.UpdateProduct(ctx, *p); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// DeleteProduct archives the product rather than removing it, so orders that
+// contain it can still resolve it. Deleting an archived product is a no-op.
+func (r *catalogService) DeleteProduct(ctx context.Context, id string) (*Product, error) {
+	p, err := r.repo.GetProductByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if p.Archived {
+		return p, nil
+	}
+
+	p.Archived = true
+	if err := r.repo.UpdateProduct(ctx, *p); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
 func (r *catalogService) GetProduct(ctx context.Context, id string) (*Product, error) {
 
 	return r.repo.GetProductByID(ctx, id)
 }
 
-func (r *catalogService) GetProducts(ctx context.Context, skip uint64, take uint64) ([]Product, error) {
-	if take > 10 || (skip == 0 && take == 0) {
+func (r *catalogService) GetProducts(ctx context.Context, skip uint64, take uint64, sort ProductSort) ([]Product, error) {
+	if take > 10 || take == 0 {
 		take = 10
 	}
 
-	return r.repo.ListProducts(ctx, skip, take)
+	return r.repo.ListProducts(ctx, skip, take, sort)
 }
 func (r *catalogService) GetProductByID(ctx context.Context, ids []string) ([]Product, error) {
 
 	return r.repo.ListProductsWithIDs(ctx, ids)
 }
-func (r *catalogService) SearchProducts(ctx context.Context, query string, skip uint64, take uint64) ([]Product, error) {
-	if take > 10 || (skip == 0 && take == 0) {
+func (r *catalogService) SearchProducts(ctx context.Context, query string, skip uint64, take uint64, sort ProductSort) ([]Product, error) {
+	if take > 10 || take == 0 {
 		take = 10
 	}
-	return r.repo.SearchProducts(ctx, query, skip, take)
+	return r.repo.SearchProducts(ctx, query, skip, take, sort)
 }
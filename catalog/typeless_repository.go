@@ -0,0 +1,415 @@
+package catalog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rajan-marasini/ecom-microservice/errs"
+)
+
+// typelessRepository talks to the Elasticsearch REST API directly using the
+// typeless endpoints (/_doc, /_search, /_mget) that Elasticsearch 7 and later
+// and OpenSearch require. It shares the index layout of elasticRepository.
+type typelessRepository struct {
+	url    string
+	client *http.Client
+}
+
+// elasticsearchError is an error response of the REST API.
+type elasticsearchError struct {
+	Status int
+	Type   string
+	Reason string
+}
+
+func (e *elasticsearchError) Error() string {
+	if e.Type == "" {
+		return fmt.Sprintf("elasticsearch: %s", http.StatusText(e.Status))
+	}
+	return fmt.Sprintf("elasticsearch: %s: %s", e.Type, e.Reason)
+}
+
+type documentHit struct {
+	ID     string          `json:"_id"`
+	Found  bool            `json:"found"`
+	Source json.RawMessage `json:"_source"`
+}
+
+func (h documentHit) product() (Product, error) {
+	p := productDocument{}
+	if err := json.Unmarshal(h.Source, &p); err != nil {
+		return Product{}, err
+	}
+	return p.product(h.ID), nil
+}
+
+func NewTypelessElasticRepository(url string) (Repository, error) {
+	r := &typelessRepository{
+		url:    strings.TrimSuffix(url, "/"),
+		client: newInstrumentedClient(10 * time.Second),
+	}
+
+	if err := r.ensureIndex(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *typelessRepository) Close() {
+	r.client.CloseIdleConnections()
+}
+
+// Ping fails while the catalog index is red, when some products can't be
+// read.
+func (r *typelessRepository) Ping(ctx context.Context) error {
+	res := struct {
+		Status string `json:"status"`
+	}{}
+	if err := r.do(ctx, http.MethodGet, "/_cluster/health/"+indexAlias, nil, &res); err != nil {
+		return err
+	}
+	if res.Status == "red" {
+		return errs.New(errs.Unavailable, "elasticsearch index %s is red", indexAlias)
+	}
+	return nil
+}
+
+func (r *typelessRepository) PutProduct(ctx context.Context, p Product) error {
+	path := fmt.Sprintf("/%s/_doc/%s", indexAlias, url.PathEscape(p.ID))
+	return r.do(ctx, http.MethodPut, path, newProductDocument(p), nil)
+}
+
+func (r *typelessRepository) UpdateProduct(ctx context.Context, p Product) error {
+	path := fmt.Sprintf("/%s/_update/%s", indexAlias, url.PathEscape(p.ID))
+
+	err := r.do(ctx, http.MethodPost, path, map[string]any{"doc": newProductDocument(p)}, nil)
+	if isStatus(err, http.StatusNotFound) {
+		return errs.New(errs.NotFound, "product %s not found", p.ID)
+	}
+	return err
+}
+
+func (r *typelessRepository) GetProductByID(ctx context.Context, id string) (*Product, error) {
+	hit := documentHit{}
+	path := fmt.Sprintf("/%s/_doc/%s", indexAlias, url.PathEscape(id))
+
+	err := r.do(ctx, http.MethodGet, path, nil, &hit)
+	if isStatus(err, http.StatusNotFound) || (err == nil && !hit.Found) {
+		return nil, errs.New(errs.NotFound, "product %s not found", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	product, err := hit.product()
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+func (r *typelessRepository) ListProducts(ctx context.Context, skip uint64, take uint64, sort ProductSort) ([]Product, error) {
+	return r.search(ctx, map[string]any{
+		"query": visibleQuery(map[string]any{"match_all": map[string]any{}}),
+		"sort":  sortClauses(sort),
+		"from":  skip,
+		"size":  take,
+	})
+}
+
+// visibleQuery is visible in REST API form.
+func visibleQuery(query map[string]any) map[string]any {
+	return map[string]any{
+		"bool": map[string]any{
+			"must":     query,
+			"must_not": map[string]any{"term": map[string]any{"archived": true}},
+		},
+	}
+}
+
+// sortClauses is elasticSorters in REST API form.
+func sortClauses(sort ProductSort) []map[string]string {
+	tieBreaker := map[string]string{"id": "asc"}
+
+	switch sort {
+	case SortPriceAsc:
+		return []map[string]string{{"price": "asc"}, tieBreaker}
+	case SortPriceDesc:
+		return []map[string]string{{"price": "desc"}, tieBreaker}
+	case SortName:
+		return []map[string]string{{"name.keyword": "asc"}, tieBreaker}
+	default:
+		return []map[string]string{{"id": "desc"}}
+	}
+}
+
+func (r *typelessRepository) ListProductsWithIDs(ctx context.Context, ids []string) ([]Product, error) {
+	products := []Product{}
+	if len(ids) == 0 {
+		return products, nil
+	}
+
+	res := struct {
+		Docs []documentHit `json:"docs"`
+	}{}
+	err := r.do(ctx, http.MethodPost, "/"+indexAlias+"/_mget", map[string]any{"ids": ids}, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, doc := range res.Docs {
+		if !doc.Found || doc.Source == nil {
+			continue
+		}
+
+		p, err := doc.product()
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+
+	return products, nil
+}
+
+func (r *typelessRepository) SearchProducts(ctx context.Context, query string, skip uint64, take uint64, sort ProductSort) ([]Product, error) {
+	return r.search(ctx, map[string]any{
+		"query": visibleQuery(map[string]any{
+			"multi_match": map[string]any{
+				"query":  query,
+				"fields": []string{"name^2", "name.autocomplete", "description"},
+			},
+		}),
+		"sort": append([]map[string]string{{"_score": "desc"}}, sortClauses(sort)...),
+		"from": skip,
+		"size": take,
+	})
+}
+
+func (r *typelessRepository) search(ctx context.Context, body map[string]any) ([]Product, error) {
+	res := struct {
+		Hits struct {
+			Hits []documentHit `json:"hits"`
+		} `json:"hits"`
+	}{}
+	if err := r.do(ctx, http.MethodPost, "/"+indexAlias+"/_search", body, &res); err != nil {
+		return nil, err
+	}
+
+	products := []Product{}
+	for _, hit := range res.Hits.Hits {
+		p, err := hit.product()
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+
+	return products, nil
+}
+
+// ensureIndex is elasticRepository.ensureIndex for the typeless API.
+func (r *typelessRepository) ensureIndex(ctx context.Context) error {
+	target := indexName(indexVersion)
+
+	err := r.do(ctx, http.MethodHead, "/"+target, nil, nil)
+	if isStatus(err, http.StatusNotFound) {
+		err = r.do(ctx, http.MethodPut, "/"+target, map[string]any{
+			"settings": indexSettings,
+			"mappings": productMapping,
+		}, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	sources, legacy, err := r.aliasedIndices(ctx)
+	if err != nil {
+		return err
+	}
+	if len(sources) == 1 && sources[0] == target {
+		return nil
+	}
+
+	actions := []map[string]any{}
+	if legacy {
+		actions = append(actions, map[string]any{
+			"remove_index": map[string]string{"index": indexAlias},
+		})
+	}
+	actions = append(actions, map[string]any{
+		"add": map[string]string{"index": target, "alias": indexAlias},
+	})
+	for _, source := range sources {
+		if source == target {
+			continue
+		}
+		if err := r.reindex(ctx, source, target); err != nil {
+			return err
+		}
+		if !legacy {
+			actions = append(actions, map[string]any{
+				"remove": map[string]string{"index": source, "alias": indexAlias},
+			})
+		}
+	}
+
+	return r.do(ctx, http.MethodPost, "/_aliases", map[string]any{"actions": actions}, nil)
+}
+
+// reindexPollInterval is how often reindex checks whether its task is done.
+var reindexPollInterval = time.Second
+
+// reindex copies the documents of source into target. It starts a task and
+// polls it instead of waiting in a single request, which would outlast the
+// client's timeout for catalogs of any size.
+func (r *typelessRepository) reindex(ctx context.Context, source, target string) error {
+	started := struct {
+		Task string `json:"task"`
+	}{}
+	err := r.do(ctx, http.MethodPost, "/_reindex?wait_for_completion=false", map[string]any{
+		"source": map[string]string{"index": source},
+		"dest":   map[string]string{"index": target},
+		"script": map[string]string{"source": copyIDScript, "lang": "painless"},
+	}, &started)
+	if err != nil {
+		return err
+	}
+
+	for {
+		task := struct {
+			Completed bool            `json:"completed"`
+			Error     json.RawMessage `json:"error"`
+			Response  struct {
+				Failures []json.RawMessage `json:"failures"`
+			} `json:"response"`
+		}{}
+		if err := r.do(ctx, http.MethodGet, "/_tasks/"+started.Task, nil, &task); err != nil {
+			return err
+		}
+
+		if task.Completed {
+			switch {
+			case task.Error != nil:
+				return fmt.Errorf("reindexing %s into %s failed: %s", source, target, task.Error)
+			case len(task.Response.Failures) != 0:
+				return fmt.Errorf("reindexing %s into %s failed: %s", source, target, task.Response.Failures[0])
+			}
+			return r.do(ctx, http.MethodPost, "/"+target+"/_refresh", nil, nil)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(reindexPollInterval):
+		}
+	}
+}
+
+// aliasedIndices returns the indices indexAlias currently resolves to, and
+// whether it is a concrete index rather than an alias.
+func (r *typelessRepository) aliasedIndices(ctx context.Context) ([]string, bool, error) {
+	res := map[string]struct {
+		Aliases map[string]json.RawMessage `json:"aliases"`
+	}{}
+
+	err := r.do(ctx, http.MethodGet, "/"+indexAlias+"/_alias", nil, &res)
+	if isStatus(err, http.StatusNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if _, ok := res[indexAlias]; ok {
+		return []string{indexAlias}, true, nil
+	}
+
+	indices := []string{}
+	for index, info := range res {
+		if _, ok := info.Aliases[indexAlias]; ok {
+			indices = append(indices, index)
+		}
+	}
+	return indices, false, nil
+}
+
+// do sends body as JSON and decodes the response into out when it is not nil.
+// Connection failures and overload responses are returned as Unavailable.
+func (r *typelessRepository) do(ctx context.Context, method, path string, body any, out any) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, r.url+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) {
+			return errs.Wrap(err, errs.Unavailable, "catalog storage unavailable")
+		}
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return responseError(res)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+func responseError(res *http.Response) error {
+	e := &elasticsearchError{Status: res.StatusCode}
+
+	body := struct {
+		Error json.RawMessage `json:"error"`
+	}{}
+	if json.NewDecoder(res.Body).Decode(&body) == nil && body.Error != nil {
+		cause := struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		}{}
+		if json.Unmarshal(body.Error, &cause) == nil {
+			e.Type, e.Reason = cause.Type, cause.Reason
+		} else {
+			json.Unmarshal(body.Error, &e.Reason)
+		}
+	}
+
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return errs.Wrap(e, errs.Unavailable, "catalog storage unavailable")
+	default:
+		return e
+	}
+}
+
+func isStatus(err error, status int) bool {
+	var e *elasticsearchError
+	return errors.As(err, &e) && e.Status == status
+}
@@ -0,0 +1,583 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rajan-marasini/ecom-microservice/errs"
+	"github.com/rajan-marasini/ecom-microservice/money"
+)
+
+// fakeElasticsearch serves the parts of the typeless REST API that
+// typelessRepository uses, keeping indices and aliases in memory. Searches
+// are recorded rather than evaluated.
+type fakeElasticsearch struct {
+	mu sync.Mutex
+	// indices maps index names to their documents by ID.
+	indices map[string]map[string]productDocument
+	// aliases maps alias names to the index they point at.
+	aliases map[string]string
+	// requests records the method and path of every request.
+	requests []string
+	// status, when set, fails every request with it.
+	status int
+	// tasks maps the IDs of started reindex tasks to how often they still
+	// report themselves running.
+	tasks map[string]int
+	// searches records the bodies of search requests.
+	searches []json.RawMessage
+	// hits are the IDs of the documents every search returns, in order.
+	hits []string
+}
+
+func newFakeElasticsearch(t *testing.T, f *fakeElasticsearch) string {
+	t.Helper()
+
+	if f.indices == nil {
+		f.indices = map[string]map[string]productDocument{}
+	}
+	if f.aliases == nil {
+		f.aliases = map[string]string{}
+	}
+	f.tasks = map[string]int{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("HEAD /{index}", f.headIndex)
+	mux.HandleFunc("PUT /{index}", f.createIndex)
+	mux.HandleFunc("GET /{index}/_alias", f.getAlias)
+	mux.HandleFunc("POST /_reindex", f.reindex)
+	mux.HandleFunc("POST /{index}/_refresh", f.refresh)
+	mux.HandleFunc("POST /_aliases", f.updateAliases)
+	mux.HandleFunc("PUT /{index}/_doc/{id}", f.putDocument)
+	mux.HandleFunc("GET /{index}/_doc/{id}", f.getDocument)
+	mux.HandleFunc("POST /{index}/_update/{id}", f.updateDocument)
+	mux.HandleFunc("POST /{index}/_mget", f.multiGet)
+	mux.HandleFunc("POST /{index}/_search", f.search)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+		if f.status != 0 {
+			writeJSON(w, f.status, map[string]any{"error": map[string]string{"type": "overloaded", "reason": "try again later"}})
+			return
+		}
+		// Task IDs are looked up here, as a route for them would overlap
+		// the one of /{index}/_alias.
+		if task, ok := strings.CutPrefix(r.URL.Path, "/_tasks/"); ok && r.Method == http.MethodGet {
+			f.getTask(w, task)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv.URL
+}
+
+func (f *fakeElasticsearch) setStatus(status int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.status = status
+}
+
+// resolve returns the index name refers to.
+func (f *fakeElasticsearch) resolve(name string) (string, bool) {
+	if index, ok := f.aliases[name]; ok {
+		return index, true
+	}
+	_, ok := f.indices[name]
+	return name, ok
+}
+
+func (f *fakeElasticsearch) headIndex(w http.ResponseWriter, r *http.Request) {
+	if _, ok := f.resolve(r.PathValue("index")); !ok {
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeElasticsearch) createIndex(w http.ResponseWriter, r *http.Request) {
+	f.indices[r.PathValue("index")] = map[string]productDocument{}
+	writeJSON(w, http.StatusOK, map[string]bool{"acknowledged": true})
+}
+
+func (f *fakeElasticsearch) getAlias(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("index")
+	index, ok := f.resolve(name)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "alias [" + name + "] missing"})
+		return
+	}
+
+	aliases := map[string]any{}
+	if index != name {
+		aliases[name] = map[string]any{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{index: map[string]any{"aliases": aliases}})
+}
+
+// reindex copies the documents at once but, like a large reindex, only
+// reports its task completed the second time it is asked.
+func (f *fakeElasticsearch) reindex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("wait_for_completion") != "false" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "reindex must run as a task"})
+		return
+	}
+
+	body := struct {
+		Source struct {
+			Index string `json:"index"`
+		} `json:"source"`
+		Dest struct {
+			Index string `json:"index"`
+		} `json:"dest"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+
+	source, _ := f.resolve(body.Source.Index)
+	for id, doc := range f.indices[source] {
+		doc.ID = id
+		f.indices[body.Dest.Index][id] = doc
+	}
+
+	task := "fake:" + strconv.Itoa(len(f.tasks)+1)
+	f.tasks[task] = 1
+	writeJSON(w, http.StatusOK, map[string]string{"task": task})
+}
+
+func (f *fakeElasticsearch) getTask(w http.ResponseWriter, task string) {
+	running, ok := f.tasks[task]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "task not found"})
+		return
+	}
+	if running > 0 {
+		f.tasks[task]--
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"completed": running == 0,
+		"response":  map[string]any{"failures": []any{}},
+	})
+}
+
+func (f *fakeElasticsearch) refresh(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{})
+}
+
+func (f *fakeElasticsearch) updateAliases(w http.ResponseWriter, r *http.Request) {
+	body := struct {
+		Actions []map[string]struct {
+			Index string `json:"index"`
+			Alias string `json:"alias"`
+		} `json:"actions"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+
+	// Like Elasticsearch, apply all actions or none.
+	indices, aliases := maps.Clone(f.indices), maps.Clone(f.aliases)
+	for _, action := range body.Actions {
+		for kind, a := range action {
+			switch kind {
+			case "remove_index":
+				delete(indices, a.Index)
+			case "add":
+				if _, ok := indices[a.Alias]; ok {
+					writeJSON(w, http.StatusBadRequest, map[string]any{"error": map[string]string{
+						"type":   "invalid_alias_name_exception",
+						"reason": "an index exists with the same name as the alias",
+					}})
+					return
+				}
+				aliases[a.Alias] = a.Index
+			case "remove":
+				if aliases[a.Alias] == a.Index {
+					delete(aliases, a.Alias)
+				}
+			}
+		}
+	}
+	f.indices, f.aliases = indices, aliases
+
+	writeJSON(w, http.StatusOK, map[string]bool{"acknowledged": true})
+}
+
+func (f *fakeElasticsearch) putDocument(w http.ResponseWriter, r *http.Request) {
+	index, _ := f.resolve(r.PathValue("index"))
+
+	doc := productDocument{}
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&doc); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": map[string]string{
+			"type":   "strict_dynamic_mapping_exception",
+			"reason": err.Error(),
+		}})
+		return
+	}
+	f.indices[index][r.PathValue("id")] = doc
+
+	writeJSON(w, http.StatusCreated, map[string]string{"result": "created"})
+}
+
+func (f *fakeElasticsearch) getDocument(w http.ResponseWriter, r *http.Request) {
+	index, _ := f.resolve(r.PathValue("index"))
+	id := r.PathValue("id")
+
+	doc, ok := f.indices[index][id]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, documentResponse(index, id, nil))
+		return
+	}
+	writeJSON(w, http.StatusOK, documentResponse(index, id, &doc))
+}
+
+func (f *fakeElasticsearch) updateDocument(w http.ResponseWriter, r *http.Request) {
+	index, _ := f.resolve(r.PathValue("index"))
+	id := r.PathValue("id")
+
+	if _, ok := f.indices[index][id]; !ok {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": map[string]string{
+			"type":   "document_missing_exception",
+			"reason": "[" + id + "]: document missing",
+		}})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"result": "noop"})
+}
+
+func (f *fakeElasticsearch) multiGet(w http.ResponseWriter, r *http.Request) {
+	index, _ := f.resolve(r.PathValue("index"))
+
+	body := struct {
+		IDs []string `json:"ids"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+
+	docs := []map[string]any{}
+	for _, id := range body.IDs {
+		if doc, ok := f.indices[index][id]; ok {
+			docs = append(docs, documentResponse(index, id, &doc))
+		} else {
+			docs = append(docs, documentResponse(index, id, nil))
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"docs": docs})
+}
+
+func (f *fakeElasticsearch) search(w http.ResponseWriter, r *http.Request) {
+	index, _ := f.resolve(r.PathValue("index"))
+
+	body := json.RawMessage{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+	f.searches = append(f.searches, body)
+
+	hits := []map[string]any{}
+	for _, id := range f.hits {
+		doc := f.indices[index][id]
+		hits = append(hits, map[string]any{"_index": index, "_id": id, "_source": doc})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"hits": map[string]any{"hits": hits}})
+}
+
+func documentResponse(index, id string, doc *productDocument) map[string]any {
+	res := map[string]any{"_index": index, "_id": id, "found": doc != nil}
+	if doc != nil {
+		res["_source"] = doc
+	}
+	return res
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func documents(products ...Product) map[string]productDocument {
+	docs := map[string]productDocument{}
+	for _, p := range products {
+		docs[p.ID] = newProductDocument(p)
+	}
+	return docs
+}
+
+// newTestRepository returns a repository backed by f, whose index is
+// already current and holds products.
+func newTestRepository(t *testing.T, f *fakeElasticsearch, products ...Product) Repository {
+	t.Helper()
+
+	f.indices = map[string]map[string]productDocument{indexName(indexVersion): documents(products...)}
+	f.aliases = map[string]string{indexAlias: indexName(indexVersion)}
+
+	r, err := NewTypelessElasticRepository(newFakeElasticsearch(t, f))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(r.Close)
+
+	return r
+}
+
+// assertJSON fails unless got holds the same JSON value as want.
+func assertJSON(t *testing.T, got json.RawMessage, want any) {
+	t.Helper()
+
+	b, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gotValue, wantValue any
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, &wantValue); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("body = %s, want %s", got, b)
+	}
+}
+
+func TestEnsureIndex(t *testing.T) {
+	reindexPollInterval = time.Millisecond
+	t.Cleanup(func() { reindexPollInterval = time.Second })
+
+	target := indexName(indexVersion)
+	// Documents indexed before the id field existed are given one.
+	legacyDoc := productDocument{Name: "Walnut desk", Price: "249.00", Currency: "USD"}
+	desk := Product{ID: "desk", Name: "Walnut desk", Price: money.New(24900, "USD")}
+
+	tests := []struct {
+		name    string
+		fake    *fakeElasticsearch
+		indices []string
+		want    []Product
+	}{
+		{
+			name:    "no index",
+			fake:    &fakeElasticsearch{},
+			indices: []string{target},
+		},
+		{
+			name: "legacy index",
+			fake: &fakeElasticsearch{
+				indices: map[string]map[string]productDocument{indexAlias: {"desk": legacyDoc}},
+			},
+			indices: []string{target},
+			want:    []Product{desk},
+		},
+		{
+			name: "old version",
+			fake: &fakeElasticsearch{
+				indices: map[string]map[string]productDocument{indexName(1): {"desk": legacyDoc}},
+				aliases: map[string]string{indexAlias: indexName(1)},
+			},
+			indices: []string{indexName(1), target},
+			want:    []Product{desk},
+		},
+		{
+			name: "current version",
+			fake: &fakeElasticsearch{
+				indices: map[string]map[string]productDocument{target: documents(desk)},
+				aliases: map[string]string{indexAlias: target},
+			},
+			indices: []string{target},
+			want:    []Product{desk},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewTypelessElasticRepository(newFakeElasticsearch(t, tt.fake))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+
+			if alias := tt.fake.aliases[indexAlias]; alias != target {
+				t.Errorf("alias points at %q, want %q", alias, target)
+			}
+			if indices := slices.Sorted(maps.Keys(tt.fake.indices)); !slices.Equal(indices, tt.indices) {
+				t.Errorf("indices = %v, want %v", indices, tt.indices)
+			}
+			for _, req := range tt.fake.requests {
+				if req == http.MethodDelete+" /"+indexAlias {
+					t.Errorf("deleted %s outside of the alias request", indexAlias)
+				}
+			}
+
+			products, err := r.ListProductsWithIDs(context.Background(), []string{"desk"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(products, tt.want) {
+				t.Errorf("products = %+v, want %+v", products, tt.want)
+			}
+		})
+	}
+}
+
+func TestPutProduct(t *testing.T) {
+	fake := &fakeElasticsearch{}
+	r := newTestRepository(t, fake)
+
+	// Dinars have three minor unit digits, which the stored decimal keeps.
+	lamp := Product{ID: "lamp", Name: "Brass lamp", Description: "Warm light", Price: money.New(12750, "KWD")}
+	if err := r.PutProduct(context.Background(), lamp); err != nil {
+		t.Fatal(err)
+	}
+
+	stored := fake.indices[indexName(indexVersion)]["lamp"]
+	if stored.Price != "12.750" || stored.Currency != "KWD" || stored.ID != "lamp" {
+		t.Errorf("stored document = %+v, want id lamp priced 12.750 KWD", stored)
+	}
+
+	got, err := r.GetProductByID(context.Background(), "lamp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != lamp {
+		t.Errorf("product = %+v, want %+v", *got, lamp)
+	}
+}
+
+func TestListProducts(t *testing.T) {
+	chair := Product{ID: "chair", Name: "Oak chair", Price: money.New(8900, "USD")}
+	stool := Product{ID: "stool", Name: "Bar stool", Price: money.New(4500, "USD")}
+
+	visible := map[string]any{
+		"bool": map[string]any{
+			"must":     map[string]any{"match_all": map[string]any{}},
+			"must_not": map[string]any{"term": map[string]any{"archived": true}},
+		},
+	}
+
+	tests := []struct {
+		name string
+		sort ProductSort
+		want []map[string]string
+	}{
+		{name: "newest", sort: SortNewest, want: []map[string]string{{"id": "desc"}}},
+		{name: "price ascending", sort: SortPriceAsc, want: []map[string]string{{"price": "asc"}, {"id": "asc"}}},
+		{name: "price descending", sort: SortPriceDesc, want: []map[string]string{{"price": "desc"}, {"id": "asc"}}},
+		{name: "name", sort: SortName, want: []map[string]string{{"name.keyword": "asc"}, {"id": "asc"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeElasticsearch{hits: []string{"stool", "chair"}}
+			r := newTestRepository(t, fake, chair, stool)
+
+			products, err := r.ListProducts(context.Background(), 20, 10, tt.sort)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := []Product{stool, chair}; !slices.Equal(products, want) {
+				t.Errorf("products = %+v, want %+v in hit order", products, want)
+			}
+
+			assertJSON(t, fake.searches[0], map[string]any{
+				"query": visible,
+				"sort":  tt.want,
+				"from":  20,
+				"size":  10,
+			})
+		})
+	}
+}
+
+func TestSearchProducts(t *testing.T) {
+	kettle := Product{ID: "kettle", Name: "Steel kettle", Description: "Boils water", Price: money.New(3500, "USD")}
+	teapot := Product{ID: "teapot", Name: "Clay teapot", Description: "For steeping tea", Price: money.New(2800, "USD")}
+
+	fake := &fakeElasticsearch{hits: []string{"teapot", "kettle"}}
+	r := newTestRepository(t, fake, kettle, teapot)
+
+	products, err := r.SearchProducts(context.Background(), "tea", 0, 5, SortPriceDesc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Product{teapot, kettle}; !slices.Equal(products, want) {
+		t.Errorf("products = %+v, want %+v in hit order", products, want)
+	}
+
+	// Relevance decides first; the requested sort only breaks ties.
+	assertJSON(t, fake.searches[0], map[string]any{
+		"query": map[string]any{
+			"bool": map[string]any{
+				"must": map[string]any{
+					"multi_match": map[string]any{
+						"query":  "tea",
+						"fields": []string{"name^2", "name.autocomplete", "description"},
+					},
+				},
+				"must_not": map[string]any{"term": map[string]any{"archived": true}},
+			},
+		},
+		"sort": []map[string]string{{"_score": "desc"}, {"price": "desc"}, {"id": "asc"}},
+		"from": 0,
+		"size": 5,
+	})
+}
+
+func TestListProductsWithIDsSkipsMissing(t *testing.T) {
+	sofa := Product{ID: "sofa", Name: "Linen sofa", Price: money.New(89900, "USD")}
+	rug := Product{ID: "rug", Name: "Wool rug", Price: money.New(19900, "USD")}
+	r := newTestRepository(t, &fakeElasticsearch{}, sofa, rug)
+
+	products, err := r.ListProductsWithIDs(context.Background(), []string{"rug", "ottoman", "sofa", "armchair"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Product{rug, sofa}; !slices.Equal(products, want) {
+		t.Errorf("products = %+v, want %+v", products, want)
+	}
+}
+
+func TestTypelessRepositoryNotFound(t *testing.T) {
+	r := newTestRepository(t, &fakeElasticsearch{})
+
+	if _, err := r.GetProductByID(context.Background(), "ottoman"); errs.KindOf(err) != errs.NotFound {
+		t.Errorf("GetProductByID: err = %v, want NotFound", err)
+	}
+	if err := r.UpdateProduct(context.Background(), Product{ID: "ottoman"}); errs.KindOf(err) != errs.NotFound {
+		t.Errorf("UpdateProduct: err = %v, want NotFound", err)
+	}
+}
+
+func TestTypelessRepositoryUnavailable(t *testing.T) {
+	for _, status := range []int{http.StatusTooManyRequests, http.StatusServiceUnavailable} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			fake := &fakeElasticsearch{}
+			r := newTestRepository(t, fake)
+
+			fake.setStatus(status)
+			if _, err := r.GetProductByID(context.Background(), "ottoman"); errs.KindOf(err) != errs.Unavailable {
+				t.Errorf("err = %v, want Unavailable", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+// Package errs defines the domain errors shared by every service and their
+// mapping to and from gRPC status codes.
+package errs
+
+import (
+	"errors"
+	"fmt"
+)
+
+type Kind int
+
+const (
+	Internal Kind = iota
+	NotFound
+	InvalidArgument
+	AlreadyExists
+	FailedPrecondition
+	Unavailable
+	Unauthenticated
+	PermissionDenied
+)
+
+func (k Kind) String() string {
+	switch k {
+	case NotFound:
+		return "NOT_FOUND"
+	case InvalidArgument:
+		return "INVALID_ARGUMENT"
+	case AlreadyExists:
+		return "ALREADY_EXISTS"
+	case FailedPrecondition:
+		return "FAILED_PRECONDITION"
+	case Unavailable:
+		return "UNAVAILABLE"
+	case Unauthenticated:
+		return "UNAUTHENTICATED"
+	case PermissionDenied:
+		return "PERMISSION_DENIED"
+	default:
+		return "INTERNAL"
+	}
+}
+
+var (
+	ErrNotFound           = &Error{Kind: NotFound}
+	ErrInvalidArgument    = &Error{Kind: InvalidArgument}
+	ErrAlreadyExists      = &Error{Kind: AlreadyExists}
+	ErrFailedPrecondition = &Error{Kind: FailedPrecondition}
+	ErrUnavailable        = &Error{Kind: Unavailable}
+	ErrUnauthenticated    = &Error{Kind: Unauthenticated}
+	ErrPermissionDenied   = &Error{Kind: PermissionDenied}
+)
+
+// Error is a domain error. The sentinel values above match any Error of the
+// same kind under errors.Is.
+type Error struct {
+	Kind       Kind
+	Message    string
+	Err        error
+	Violations []Violation
+}
+
+// Violation describes one invalid input field. Field is a path such as
+// "products[2].quantity".
+type Violation struct {
+	Field       string
+	Description string
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Message != "" && e.Err != nil:
+		return e.Message + ": " + e.Err.Error()
+	case e.Message != "":
+		return e.Message
+	case e.Err != nil:
+		return e.Err.Error()
+	default:
+		return e.Kind.String()
+	}
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Message == "" && t.Err == nil && t.Kind == e.Kind
+}
+
+func New(kind Kind, format string, args ...any) *Error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...)}
+}
+
+// Wrap attaches kind and message to err.
+func Wrap(err error, kind Kind, format string, args ...any) *Error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...), Err: err}
+}
+
+// Invalid returns an InvalidArgument error listing violations.
+func Invalid(message string, violations ...Violation) *Error {
+	return &Error{Kind: InvalidArgument, Message: message, Violations: violations}
+}
+
+// ViolationsOf returns the field violations of the first Error in err's
+// chain.
+func ViolationsOf(err error) []Violation {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Violations
+	}
+	return nil
+}
+
+// KindOf returns the kind of the first Error in err's chain, or Internal.
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return Internal
+}
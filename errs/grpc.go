@@ -0,0 +1,120 @@
+package errs
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+const domain = "ecom-microservice"
+
+var kindCodes = map[Kind]codes.Code{
+	Internal:           codes.Internal,
+	NotFound:           codes.NotFound,
+	InvalidArgument:    codes.InvalidArgument,
+	AlreadyExists:      codes.AlreadyExists,
+	FailedPrecondition: codes.FailedPrecondition,
+	Unavailable:        codes.Unavailable,
+	Unauthenticated:    codes.Unauthenticated,
+	PermissionDenied:   codes.PermissionDenied,
+}
+
+// ToGRPC converts err into a gRPC status error. Errors that already carry a
+// status are returned unchanged; domain errors get their matching code and an
+// ErrorInfo detail naming the kind.
+func ToGRPC(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+
+	kind := KindOf(err)
+	message := err.Error()
+	if kind == Internal {
+		message = "internal error"
+	}
+
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{
+		Reason: kind.String(),
+		Domain: domain,
+	}}
+	if violations := ViolationsOf(err); len(violations) != 0 {
+		badRequest := &errdetails.BadRequest{}
+		for _, v := range violations {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       v.Field,
+				Description: v.Description,
+			})
+		}
+		details = append(details, badRequest)
+	}
+
+	st, detailErr := status.New(kindCodes[kind], message).WithDetails(details...)
+	if detailErr != nil {
+		return status.Error(kindCodes[kind], message)
+	}
+	return st.Err()
+}
+
+// FromGRPC converts a gRPC status error returned by a client call back into
+// a domain error so it can cross the next service boundary with its kind.
+func FromGRPC(err error) error {
+	if err == nil {
+		return nil
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	kind := Internal
+	switch st.Code() {
+	case codes.NotFound:
+		kind = NotFound
+	case codes.InvalidArgument, codes.OutOfRange:
+		kind = InvalidArgument
+	case codes.AlreadyExists:
+		kind = AlreadyExists
+	case codes.FailedPrecondition, codes.Aborted:
+		kind = FailedPrecondition
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted:
+		kind = Unavailable
+	case codes.Unauthenticated:
+		kind = Unauthenticated
+	case codes.PermissionDenied:
+		kind = PermissionDenied
+	}
+
+	e := &Error{Kind: kind, Message: st.Message()}
+	for _, detail := range st.Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			for _, v := range badRequest.FieldViolations {
+				e.Violations = append(e.Violations, Violation{
+					Field:       v.Field,
+					Description: v.Description,
+				})
+			}
+		}
+	}
+
+	return e
+}
+
+// UnaryServerInterceptor maps handler errors to gRPC status codes with ToGRPC.
+func UnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return nil, ToGRPC(err)
+	}
+	return resp, nil
+}
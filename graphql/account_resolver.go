@@ -1,44 +1,24 @@
 package main
 
-import (
-	"context"
-	"log"
-	"time"
-)
+import "context"
 
 type accountResolver struct {
 	server *Server
 }
 
 func (r *accountResolver) Orders(ctx context.Context, obj *Account) ([]*Order, error) {
-	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
-	defer cancel()
+	if err := authorizeAccount(ctx, obj.ID); err != nil {
+		return nil, err
+	}
 
 	orderList, err := r.server.orderClient.GetOrdersForAccount(ctx, obj.ID)
 	if err != nil {
-		log.Println(err)
 		return nil, err
 	}
 
 	var orders []*Order
 	for _, o := range orderList {
-		var products []*OrderedProduct
-
-		for _, p := range o.Products {
-			products = append(products, &OrderedProduct{
-				ID:          p.ID,
-				Name:        p.Name,
-				Description: p.Description,
-				Price:       p.Price,
-				Quantity:    int(p.Quantity),
-			})
-		}
-		orders = append(orders, &Order{
-			ID:         o.ID,
-			CreatedAt:  o.CreatedAt,
-			TotalPrice: o.TotalPrice,
-			Products:   products,
-		})
+		orders = append(orders, newOrder(o))
 	}
 	return orders, nil
 }
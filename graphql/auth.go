@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/rajan-marasini/ecom-microservice/account"
+	"github.com/rajan-marasini/ecom-microservice/auth"
+	"github.com/rajan-marasini/ecom-microservice/errs"
+)
+
+type identityKey struct{}
+
+// authenticate verifies the bearer token of each request with the account
+// service and stores the caller's account in the request context. Requests
+// without an Authorization header continue anonymously; requests with an
+// invalid one are rejected.
+func authenticate(accounts *account.Client, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		scheme, token, _ := strings.Cut(header, " ")
+		if !strings.EqualFold(scheme, "Bearer") || token == "" {
+			writeAuthError(w, http.StatusUnauthorized, errs.New(errs.Unauthenticated, "malformed authorization header"))
+			return
+		}
+
+		acc, err := accounts.VerifyToken(r.Context(), token)
+		switch {
+		case errors.Is(err, errs.ErrUnauthenticated):
+			writeAuthError(w, http.StatusUnauthorized, err)
+			return
+		case err != nil:
+			slog.ErrorContext(r.Context(), "verifying token failed", "error", err)
+			writeAuthError(w, http.StatusServiceUnavailable, errs.New(errs.Unavailable, "cannot verify token"))
+			return
+		}
+
+		// The identity also travels with every call to the services, which
+		// check it for methods that require a role.
+		roles := []string{}
+		for _, role := range acc.Roles {
+			roles = append(roles, string(role))
+		}
+		ctx := context.WithValue(r.Context(), identityKey{}, acc)
+		ctx = auth.NewOutgoingContext(ctx, auth.Identity{AccountID: acc.ID, Roles: roles})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// writeAuthError responds in the shape of a GraphQL error so clients can
+// handle it like any other.
+func writeAuthError(w http.ResponseWriter, status int, err error) {
+	if status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"errors": []map[string]any{{
+			"message":    err.Error(),
+			"extensions": map[string]any{"code": errs.KindOf(err).String()},
+		}},
+	})
+}
+
+// identity returns the authenticated caller, if any.
+func identity(ctx context.Context) (*account.Account, bool) {
+	acc, ok := ctx.Value(identityKey{}).(*account.Account)
+	return acc, ok
+}
+
+func requireIdentity(ctx context.Context) (*account.Account, error) {
+	acc, ok := identity(ctx)
+	if !ok {
+		return nil, errs.New(errs.Unauthenticated, "authentication required")
+	}
+	return acc, nil
+}
+
+// authorizeAccount allows callers to act on their own account, and admins on
+// any account.
+func authorizeAccount(ctx context.Context, accountID string) error {
+	caller, err := requireIdentity(ctx)
+	if err != nil {
+		return err
+	}
+	if caller.ID != accountID && !caller.HasRole(account.RoleAdmin) {
+		return errs.New(errs.PermissionDenied, "not allowed to access account %s", accountID)
+	}
+	return nil
+}
+
+// requireRole is the @hasRole check, for fields that need the role only for
+// some of their arguments.
+func requireRole(ctx context.Context, role Role) error {
+	caller, err := requireIdentity(ctx)
+	if err != nil {
+		return err
+	}
+	if !caller.HasRole(role.AccountRole()) {
+		return errs.New(errs.PermissionDenied, "requires role %s", role)
+	}
+	return nil
+}
+
+// hasRole implements the @hasRole directive.
+func hasRole(ctx context.Context, obj any, next graphql.Resolver, role Role) (any, error) {
+	if err := requireRole(ctx, role); err != nil {
+		return nil, err
+	}
+	return next(ctx)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/rajan-marasini/ecom-microservice/errs"
+	"github.com/rajan-marasini/ecom-microservice/logging"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// presentError adds the domain error kind as extensions.code so clients can
+// branch on it without parsing messages, and lists field violations under
+// extensions.violations. Every error is logged with the request it failed.
+func presentError(ctx context.Context, err error) *gqlerror.Error {
+	gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+	slog.Log(ctx, logging.LevelOf(err), "graphql request failed",
+		"path", gqlErr.Path.String(),
+		"code", errs.KindOf(err).String(),
+		"error", err,
+	)
+
+	if gqlErr.Extensions == nil {
+		gqlErr.Extensions = map[string]any{}
+	}
+	if _, ok := gqlErr.Extensions["code"]; !ok {
+		gqlErr.Extensions["code"] = errs.KindOf(err).String()
+	}
+
+	if violations := errs.ViolationsOf(err); len(violations) != 0 {
+		fields := []map[string]string{}
+		for _, v := range violations {
+			fields = append(fields, map[string]string{
+				"field":       v.Field,
+				"description": v.Description,
+			})
+		}
+		gqlErr.Extensions["violations"] = fields
+	}
+
+	return gqlErr
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/99designs/gqlgen/graphql"
 	"github.com/rajan-marasini/ecom-microservice/account"
 	"github.com/rajan-marasini/ecom-microservice/catalog"
+	"github.com/rajan-marasini/ecom-microservice/grpcclient"
 	"github.com/rajan-marasini/ecom-microservice/order"
 )
 
@@ -13,19 +14,19 @@ type Server struct {
 	orderClient   *order.Client
 }
 
-func NewGraphQLServer(accountUrl, catalogUrl, orderUrl string) (*Server, error) {
-	accountClient, err := account.NewClient(accountUrl)
+func NewGraphQLServer(accountUrl, catalogUrl, orderUrl string, opts grpcclient.Options) (*Server, error) {
+	accountClient, err := account.NewClient(accountUrl, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	catalogClient, err := catalog.NewClient(catalogUrl)
+	catalogClient, err := catalog.NewClient(catalogUrl, opts)
 	if err != nil {
 		accountClient.Close()
 		return nil, err
 	}
 
-	orderClient, err := order.NewClient(orderUrl)
+	orderClient, err := order.NewClient(orderUrl, opts)
 	if err != nil {
 		accountClient.Close()
 		catalogClient.Close()
@@ -39,6 +40,13 @@ func NewGraphQLServer(accountUrl, catalogUrl, orderUrl string) (*Server, error)
 	}, nil
 }
 
+// Close closes the service clients. Call it once no requests are running.
+func (s *Server) Close() {
+	s.orderClient.Close()
+	s.catalogClient.Close()
+	s.accountClient.Close()
+}
+
 func (s *Server) Mutation() MutationResolver {
 	return &mutationResolver{
 		server: s,
@@ -60,5 +68,8 @@ func (s *Server) Account() AccountResolver {
 func (s *Server) toExecutableSchema() graphql.ExecutableSchema {
 	return NewExecutableSchema(Config{
 		Resolvers: s,
+		Directives: DirectiveRoot{
+			HasRole: hasRole,
+		},
 	})
 }
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// healthz reports that the gateway is up. It does not call the services,
+// since restarting the gateway would not help when one of them is down or
+// slow; readyz covers them.
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, "ok", nil)
+}
+
+// readyz reports whether the gateway can serve requests, which needs all
+// three services.
+func (s *Server) readyz(w http.ResponseWriter, r *http.Request) {
+	backends := s.backendHealth(r.Context())
+	for _, status := range backends {
+		if status != "ok" {
+			writeHealth(w, http.StatusServiceUnavailable, "unavailable", backends)
+			return
+		}
+	}
+	writeHealth(w, http.StatusOK, "ok", backends)
+}
+
+// backendHealth checks the services concurrently and returns "ok" or the
+// error for each.
+func (s *Server) backendHealth(ctx context.Context) map[string]string {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	checks := map[string]func(context.Context) error{
+		"account": s.accountClient.Health,
+		"catalog": s.catalogClient.Health,
+		"order":   s.orderClient.Health,
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	backends := map[string]string{}
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status := "ok"
+			if err := check(ctx); err != nil {
+				status = err.Error()
+			}
+			mu.Lock()
+			backends[name] = status
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	return backends
+}
+
+func writeHealth(w http.ResponseWriter, code int, status string, backends map[string]string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	body := map[string]any{"status": status}
+	if backends != nil {
+		body["backends"] = backends
+	}
+	json.NewEncoder(w).Encode(body)
+}
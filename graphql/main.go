@@ -1,18 +1,44 @@
 package main
 
 import (
+	"context"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/kelseyhightower/envconfig"
+	"github.com/rajan-marasini/ecom-microservice/grpcclient"
+	"github.com/rajan-marasini/ecom-microservice/logging"
+	"github.com/rajan-marasini/ecom-microservice/metrics"
+	"github.com/rajan-marasini/ecom-microservice/mtls"
+	"github.com/rajan-marasini/ecom-microservice/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 type AppConfig struct {
 	AccountURL string `envconfig:"ACCOUNT_SERVICE_URL"`
 	CatalogURL string `envconfig:"CATALOG_SERVICE_URL"`
 	OrderURL   string `envconfig:"ORDER_SERVICE_URL"`
+	// TLS enables mutual TLS to the services with TLS_CERT_FILE, TLS_KEY_FILE
+	// and TLS_CA_FILE.
+	TLS mtls.Config `envconfig:"TLS"`
+	// Client tunes the calls to the other services with CLIENT_TIMEOUT,
+	// CLIENT_MAX_ATTEMPTS, CLIENT_BREAKER_FAILURES and CLIENT_BREAKER_TIMEOUT.
+	Client grpcclient.Config `envconfig:"CLIENT"`
+	// MetricsPort serves Prometheus metrics at /metrics, apart from the public
+	// GraphQL port.
+	MetricsPort int `envconfig:"METRICS_PORT" default:"9090"`
+	// ShutdownTimeout bounds how long in-flight requests may run after SIGTERM.
+	ShutdownTimeout time.Duration `envconfig:"SHUTDOWN_TIMEOUT" default:"15s"`
+	// Log sets the lowest level logged with LOG_LEVEL (debug, info, warn or
+	// error) and the format with LOG_FORMAT (json or text).
+	Log logging.Config `envconfig:"LOG"`
 }
 
 func main() {
@@ -22,13 +48,74 @@ func main() {
 		log.Fatal(err)
 	}
 
-	s, err := NewGraphQLServer(cfg.AccountURL, cfg.CatalogURL, cfg.OrderURL)
-	if err != nil {
+	if err := logging.Init(cfg.Log, "graphql"); err != nil {
 		log.Fatal(err)
 	}
 
-	http.Handle("/graphql", handler.NewDefaultServer(s.toExecutableSchema()))
-	http.Handle("/playground", playground.Handler("rajan", "/graphql"))
+	creds, err := mtls.Load(cfg.TLS)
+	if err != nil {
+		logging.Fatal("loading TLS credentials failed", "error", err)
+	}
+
+	shutdownTracing, err := tracing.Init(context.Background(), "graphql")
+	if err != nil {
+		logging.Fatal("initializing tracing failed", "error", err)
+	}
+
+	s, err := NewGraphQLServer(cfg.AccountURL, cfg.CatalogURL, cfg.OrderURL, grpcclient.Options{Config: cfg.Client, Credentials: creds})
+	if err != nil {
+		logging.Fatal("connecting to services failed", "error", err)
+	}
+
+	srv := handler.NewDefaultServer(s.toExecutableSchema())
+	srv.SetErrorPresenter(presentError)
+	srv.Use(resolverMetrics{})
+	srv.Use(resolverTracing{})
 
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	mux := http.NewServeMux()
+	mux.Handle("/graphql", logging.Middleware(authenticate(s.accountClient, srv)))
+	mux.Handle("/playground", playground.Handler("rajan", "/graphql"))
+	mux.HandleFunc("/healthz", s.healthz)
+	mux.HandleFunc("/readyz", s.readyz)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		slog.Error("metrics server stopped", "error", metrics.ListenAndServe(cfg.MetricsPort))
+	}()
+
+	// Requests start a trace, or continue the caller's from its traceparent
+	// header. Probes are not traced.
+	traced := otelhttp.NewHandler(mux, "graphql", otelhttp.WithFilter(func(r *http.Request) bool {
+		switch r.URL.Path {
+		case "/healthz", "/readyz":
+			return false
+		}
+		return true
+	}))
+
+	httpServer := &http.Server{Addr: ":8080", Handler: traced}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.ListenAndServe()
+	}()
+	slog.Info("listening", "port", 8080)
+
+	select {
+	case <-ctx.Done():
+		slog.Info("shutting down")
+	case err = <-serveErr:
+		s.Close()
+		logging.Fatal("serving HTTP failed", "error", err)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	err = httpServer.Shutdown(shutdownCtx)
+	s.Close()
+	shutdownTracing(shutdownCtx)
+	if err != nil {
+		logging.Fatal("shutdown failed", "error", err)
+	}
 }
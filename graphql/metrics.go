@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rajan-marasini/ecom-microservice/errs"
+)
+
+var resolverDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "graphql_resolver_seconds",
+	Help:    "Time taken by GraphQL resolvers, by object, field and error code.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"object", "field", "code"})
+
+// resolverMetrics is a gqlgen extension that records the latency of every
+// field backed by a resolver. Plain struct fields are left out.
+type resolverMetrics struct{}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.FieldInterceptor
+} = resolverMetrics{}
+
+func (resolverMetrics) ExtensionName() string {
+	return "ResolverMetrics"
+}
+
+func (resolverMetrics) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (resolverMetrics) InterceptField(ctx context.Context, next graphql.Resolver) (any, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if !fc.IsResolver {
+		return next(ctx)
+	}
+
+	start := time.Now()
+	res, err := next(ctx)
+
+	code := "OK"
+	if err != nil {
+		code = errs.KindOf(err).String()
+	}
+	resolverDuration.WithLabelValues(fc.Object, fc.Field.Name, code).Observe(time.Since(start).Seconds())
+	return res, err
+}
@@ -1,9 +1,90 @@
 package main
 
+import (
+	"strings"
+	"time"
+
+	"github.com/rajan-marasini/ecom-microservice/account"
+	"github.com/rajan-marasini/ecom-microservice/catalog"
+	"github.com/rajan-marasini/ecom-microservice/money"
+	"github.com/rajan-marasini/ecom-microservice/order"
+)
+
 type Account struct {
-	ID    string  `json:"id"`
-	Name  string  `json:"name"`
-	Order []Order `json:"orders"`
+	ID        string        `json:"id"`
+	Name      string        `json:"name"`
+	Email     string        `json:"email"`
+	Phone     string        `json:"phone"`
+	Status    AccountStatus `json:"status"`
+	Roles     []Role        `json:"roles"`
+	CreatedAt time.Time     `json:"createdAt"`
+	UpdatedAt time.Time     `json:"updatedAt"`
+	Order     []Order       `json:"orders"`
+}
+
+func newAccount(a account.Account) *Account {
+	return &Account{
+		ID:        a.ID,
+		Name:      a.Name,
+		Email:     a.Email,
+		Phone:     a.Phone,
+		Status:    newAccountStatus(a.Status),
+		Roles:     newRoles(a.Roles),
+		CreatedAt: a.CreatedAt,
+		UpdatedAt: a.UpdatedAt,
+	}
+}
+
+func newAuthPayload(a account.Account, t account.Tokens) *AuthPayload {
+	return &AuthPayload{
+		Account:      newAccount(a),
+		AccessToken:  t.AccessToken,
+		RefreshToken: t.RefreshToken,
+		ExpiresAt:    t.ExpiresAt,
+	}
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func newAccountStatus(s account.Status) AccountStatus {
+	switch s {
+	case account.StatusSuspended:
+		return AccountStatusSuspended
+	case account.StatusClosed:
+		return AccountStatusClosed
+	default:
+		return AccountStatusActive
+	}
+}
+
+func (s AccountStatus) ServiceStatus() account.Status {
+	switch s {
+	case AccountStatusSuspended:
+		return account.StatusSuspended
+	case AccountStatusClosed:
+		return account.StatusClosed
+	default:
+		return account.StatusActive
+	}
+}
+
+func newRoles(roles []account.Role) []Role {
+	result := []Role{}
+	for _, role := range roles {
+		if r := Role(strings.ToUpper(string(role))); r.IsValid() {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+func (r Role) AccountRole() account.Role {
+	return account.Role(strings.ToLower(string(r)))
 }
 
 func (p *PaginationInput) Bound() (uint64, uint64) {
@@ -17,3 +98,96 @@ func (p *PaginationInput) Bound() (uint64, uint64) {
 	}
 	return skip, take
 }
+
+func (s ProductSort) CatalogSort() catalog.ProductSort {
+	switch s {
+	case ProductSortPriceAsc:
+		return catalog.SortPriceAsc
+	case ProductSortPriceDesc:
+		return catalog.SortPriceDesc
+	case ProductSortName:
+		return catalog.SortName
+	default:
+		return catalog.SortNewest
+	}
+}
+
+func newMoney(m money.Money) *Money {
+	return &Money{
+		Amount:   m.Decimal(),
+		Currency: m.Currency,
+	}
+}
+
+func (m *MoneyInput) Money() (money.Money, error) {
+	currency := money.DefaultCurrency
+	if m.Currency != nil {
+		currency = *m.Currency
+	}
+	return money.Parse(m.Amount, currency)
+}
+
+func newProduct(p catalog.Product) *Product {
+	return &Product{
+		ID:          p.ID,
+		Name:        p.Name,
+		Description: p.Description,
+		Price:       newMoney(p.Price),
+		Archived:    p.Archived,
+	}
+}
+
+func newOrder(o order.Order) *Order {
+	var products []*OrderedProduct
+	for _, p := range o.Products {
+		products = append(products, &OrderedProduct{
+			ID:          p.ID,
+			Name:        p.Name,
+			Description: p.Description,
+			Price:       newMoney(p.Price),
+			Quantity:    int(p.Quantity),
+		})
+	}
+
+	history := []*OrderStatusChange{}
+	for _, c := range o.History {
+		history = append(history, &OrderStatusChange{
+			Status:    newOrderStatus(c.Status),
+			ChangedAt: c.ChangedAt,
+			ActorID:   c.ActorID,
+		})
+	}
+
+	return &Order{
+		ID:         o.ID,
+		AccountID:  o.AccountID,
+		CreatedAt:  o.CreatedAt,
+		TotalPrice: newMoney(o.TotalPrice),
+		Products:   products,
+		Status:     newOrderStatus(o.Status),
+		History:    history,
+	}
+}
+
+var orderStatuses = map[order.Status]OrderStatus{
+	order.StatusPending:   OrderStatusPending,
+	order.StatusConfirmed: OrderStatusConfirmed,
+	order.StatusPaid:      OrderStatusPaid,
+	order.StatusShipped:   OrderStatusShipped,
+	order.StatusDelivered: OrderStatusDelivered,
+	order.StatusCancelled: OrderStatusCancelled,
+	order.StatusRefunded:  OrderStatusRefunded,
+}
+
+func newOrderStatus(s order.Status) OrderStatus {
+	return orderStatuses[s]
+}
+
+func (s OrderStatus) ServiceStatus() order.Status {
+	for status, name := range orderStatuses {
+		if name == s {
+			return status
+		}
+	}
+	return order.StatusPending
+}
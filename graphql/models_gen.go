@@ -3,26 +3,84 @@
 package main
 
 import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
 	"time"
 )
 
+// Accounts created without a password cannot log in.
 type AccountInput struct {
-	Name string `json:"name"`
+	Name     string  `json:"name"`
+	Email    string  `json:"email"`
+	Phone    *string `json:"phone,omitempty"`
+	Password *string `json:"password,omitempty"`
 }
 
+// Fields that are omitted or null are left unchanged. Use deleteAccount to
+// close an account.
+type AccountUpdateInput struct {
+	Name  *string `json:"name,omitempty"`
+	Email *string `json:"email,omitempty"`
+	Phone *string `json:"phone,omitempty"`
+	// Suspends or reactivates the account.
+	Status *AccountStatus `json:"status,omitempty"`
+	// Replaces the account's roles. Every account keeps CUSTOMER.
+	Roles []Role `json:"roles,omitempty"`
+}
+
+// Send accessToken as "Authorization: Bearer <token>". When it expires at
+// expiresAt, exchange refreshToken for a new pair with the refreshToken mutation.
+type AuthPayload struct {
+	Account      *Account  `json:"account"`
+	AccessToken  string    `json:"accessToken"`
+	RefreshToken string    `json:"refreshToken"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+}
+
+type LoginInput struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// An exact amount of money. amount is a decimal string in major units, e.g.
+// "19.99", and currency is an ISO 4217 code.
+type Money struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// currency defaults to USD when omitted.
+type MoneyInput struct {
+	Amount   string  `json:"amount"`
+	Currency *string `json:"currency,omitempty"`
+}
+
+// Mutations and fields that act on an account's data require its access token,
+// or one of an admin account.
 type Mutation struct {
 }
 
 type Order struct {
 	ID         string            `json:"id"`
+	AccountID  string            `json:"accountId"`
 	CreatedAt  time.Time         `json:"createdAt"`
-	TotalPrice float64           `json:"totalPrice"`
+	TotalPrice *Money            `json:"totalPrice"`
 	Products   []*OrderedProduct `json:"products"`
+	Status     OrderStatus       `json:"status"`
+	// Every status the order has had, oldest first.
+	History []*OrderStatusChange `json:"history"`
 }
 
 type OrderInput struct {
 	AccountID string               `json:"accountId"`
 	Products  []*OrderProductInput `json:"products"`
+	// A unique key, such as a UUID, chosen by the client for this order. Retrying
+	// createOrder with the same key returns the order placed first instead of
+	// placing another; reusing it with different products is a
+	// FAILED_PRECONDITION error.
+	IdempotencyKey *string `json:"idempotencyKey,omitempty"`
 }
 
 type OrderProductInput struct {
@@ -30,12 +88,19 @@ type OrderProductInput struct {
 	Quantity int    `json:"quantity"`
 }
 
+type OrderStatusChange struct {
+	Status    OrderStatus `json:"status"`
+	ChangedAt time.Time   `json:"changedAt"`
+	// The account that made the change.
+	ActorID string `json:"actorId"`
+}
+
 type OrderedProduct struct {
-	ID          string  `json:"id"`
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
-	Quantity    int     `json:"quantity"`
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Price       *Money `json:"price"`
+	Quantity    int    `json:"quantity"`
 }
 
 type PaginationInput struct {
@@ -44,17 +109,276 @@ type PaginationInput struct {
 }
 
 type Product struct {
-	ID          string  `json:"id"`
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Price       *Money `json:"price"`
+	// Archived products are hidden from listings and search but can still be
+	// fetched by id.
+	Archived bool `json:"archived"`
 }
 
 type ProductInput struct {
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Price       *MoneyInput `json:"price"`
+}
+
+// Fields that are omitted or null are left unchanged.
+type ProductUpdateInput struct {
+	Name        *string     `json:"name,omitempty"`
+	Description *string     `json:"description,omitempty"`
+	Price       *MoneyInput `json:"price,omitempty"`
 }
 
 type Query struct {
 }
+
+type RegisterInput struct {
+	Name     string  `json:"name"`
+	Email    string  `json:"email"`
+	Phone    *string `json:"phone,omitempty"`
+	Password string  `json:"password"`
+}
+
+type AccountStatus string
+
+const (
+	AccountStatusActive    AccountStatus = "ACTIVE"
+	AccountStatusSuspended AccountStatus = "SUSPENDED"
+	AccountStatusClosed    AccountStatus = "CLOSED"
+)
+
+var AllAccountStatus = []AccountStatus{
+	AccountStatusActive,
+	AccountStatusSuspended,
+	AccountStatusClosed,
+}
+
+func (e AccountStatus) IsValid() bool {
+	switch e {
+	case AccountStatusActive, AccountStatusSuspended, AccountStatusClosed:
+		return true
+	}
+	return false
+}
+
+func (e AccountStatus) String() string {
+	return string(e)
+}
+
+func (e *AccountStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = AccountStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid AccountStatus", str)
+	}
+	return nil
+}
+
+func (e AccountStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *AccountStatus) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e AccountStatus) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+// Orders start out PENDING and move through CONFIRMED, PAID, SHIPPED and
+// DELIVERED. They can be CANCELLED until paid for, and REFUNDED once paid.
+// CANCELLED and REFUNDED are final.
+type OrderStatus string
+
+const (
+	OrderStatusPending   OrderStatus = "PENDING"
+	OrderStatusConfirmed OrderStatus = "CONFIRMED"
+	OrderStatusPaid      OrderStatus = "PAID"
+	OrderStatusShipped   OrderStatus = "SHIPPED"
+	OrderStatusDelivered OrderStatus = "DELIVERED"
+	OrderStatusCancelled OrderStatus = "CANCELLED"
+	OrderStatusRefunded  OrderStatus = "REFUNDED"
+)
+
+var AllOrderStatus = []OrderStatus{
+	OrderStatusPending,
+	OrderStatusConfirmed,
+	OrderStatusPaid,
+	OrderStatusShipped,
+	OrderStatusDelivered,
+	OrderStatusCancelled,
+	OrderStatusRefunded,
+}
+
+func (e OrderStatus) IsValid() bool {
+	switch e {
+	case OrderStatusPending, OrderStatusConfirmed, OrderStatusPaid, OrderStatusShipped, OrderStatusDelivered, OrderStatusCancelled, OrderStatusRefunded:
+		return true
+	}
+	return false
+}
+
+func (e OrderStatus) String() string {
+	return string(e)
+}
+
+func (e *OrderStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = OrderStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid OrderStatus", str)
+	}
+	return nil
+}
+
+func (e OrderStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *OrderStatus) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e OrderStatus) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+// The order of products. Search results are ordered by relevance first.
+type ProductSort string
+
+const (
+	ProductSortNewest    ProductSort = "NEWEST"
+	ProductSortPriceAsc  ProductSort = "PRICE_ASC"
+	ProductSortPriceDesc ProductSort = "PRICE_DESC"
+	ProductSortName      ProductSort = "NAME"
+)
+
+var AllProductSort = []ProductSort{
+	ProductSortNewest,
+	ProductSortPriceAsc,
+	ProductSortPriceDesc,
+	ProductSortName,
+}
+
+func (e ProductSort) IsValid() bool {
+	switch e {
+	case ProductSortNewest, ProductSortPriceAsc, ProductSortPriceDesc, ProductSortName:
+		return true
+	}
+	return false
+}
+
+func (e ProductSort) String() string {
+	return string(e)
+}
+
+func (e *ProductSort) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ProductSort(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ProductSort", str)
+	}
+	return nil
+}
+
+func (e ProductSort) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *ProductSort) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e ProductSort) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type Role string
+
+const (
+	RoleCustomer Role = "CUSTOMER"
+	RoleMerchant Role = "MERCHANT"
+	RoleAdmin    Role = "ADMIN"
+)
+
+var AllRole = []Role{
+	RoleCustomer,
+	RoleMerchant,
+	RoleAdmin,
+}
+
+func (e Role) IsValid() bool {
+	switch e {
+	case RoleCustomer, RoleMerchant, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+func (e Role) String() string {
+	return string(e)
+}
+
+func (e *Role) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = Role(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid Role", str)
+	}
+	return nil
+}
+
+func (e Role) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *Role) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e Role) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
@@ -2,15 +2,12 @@ package main
 
 import (
 	"context"
-	"errors"
-	"log"
-	"time"
 
+	"github.com/rajan-marasini/ecom-microservice/account"
+	"github.com/rajan-marasini/ecom-microservice/catalog"
+	"github.com/rajan-marasini/ecom-microservice/errs"
 	"github.com/rajan-marasini/ecom-microservice/order"
-)
-
-var (
-	ErrInvalidParameter = errors.New("Invalid Parameter")
+	"github.com/rajan-marasini/ecom-microservice/validation"
 )
 
 type mutationResolver struct {
@@ -18,64 +15,199 @@ type mutationResolver struct {
 }
 
 func (r *mutationResolver) CreateAccount(ctx context.Context, in AccountInput) (*Account, error) {
-	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
-	defer cancel()
+	acc, err := r.server.accountClient.PostAccount(ctx, in.Name, in.Email, stringValue(in.Phone), stringValue(in.Password))
+	if err != nil {
+		return nil, err
+	}
+
+	return newAccount(*acc), nil
+}
+
+func (r *mutationResolver) Register(ctx context.Context, in RegisterInput) (*AuthPayload, error) {
+	if _, err := r.server.accountClient.PostAccount(ctx, in.Name, in.Email, stringValue(in.Phone), in.Password); err != nil {
+		return nil, err
+	}
+
+	acc, tokens, err := r.server.accountClient.Login(ctx, in.Email, in.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	return newAuthPayload(*acc, *tokens), nil
+}
+
+func (r *mutationResolver) Login(ctx context.Context, in LoginInput) (*AuthPayload, error) {
+	acc, tokens, err := r.server.accountClient.Login(ctx, in.Email, in.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	return newAuthPayload(*acc, *tokens), nil
+}
+
+func (r *mutationResolver) RefreshToken(ctx context.Context, refreshToken string) (*AuthPayload, error) {
+	acc, tokens, err := r.server.accountClient.RefreshToken(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return newAuthPayload(*acc, *tokens), nil
+}
+
+func (r *mutationResolver) UpdateAccount(ctx context.Context, id string, in AccountUpdateInput) (*Account, error) {
+	if err := authorizeAccount(ctx, id); err != nil {
+		return nil, err
+	}
 
-	acc, err := r.server.accountClient.PostAccount(ctx, in.Name)
+	update := account.AccountUpdate{
+		Name:  in.Name,
+		Email: in.Email,
+		Phone: in.Phone,
+	}
+	if in.Status != nil {
+		status := in.Status.ServiceStatus()
+		update.Status = &status
+	}
+	if in.Roles != nil {
+		roles := []account.Role{}
+		for _, role := range in.Roles {
+			roles = append(roles, role.AccountRole())
+		}
+		update.Roles = &roles
+	}
+
+	acc, err := r.server.accountClient.UpdateAccount(ctx, id, update)
 	if err != nil {
-		log.Println(err)
 		return nil, err
 	}
 
-	return &Account{
-		ID:   acc.ID,
-		Name: acc.Name,
-	}, nil
+	return newAccount(*acc), nil
+}
+
+func (r *mutationResolver) ChangePassword(ctx context.Context, id string, currentPassword *string, newPassword string) (*Account, error) {
+	if err := authorizeAccount(ctx, id); err != nil {
+		return nil, err
+	}
+	// Only admins may reset the password of another account; owners prove
+	// they know the current one.
+	if caller, _ := identity(ctx); caller.ID == id && currentPassword == nil {
+		return nil, errs.Invalid("invalid password change", errs.Violation{
+			Field:       "currentPassword",
+			Description: "is required to change your own password",
+		})
+	}
+
+	acc, err := r.server.accountClient.ChangePassword(ctx, id, currentPassword, newPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	return newAccount(*acc), nil
+}
+
+func (r *mutationResolver) DeleteAccount(ctx context.Context, id string) (*Account, error) {
+	if err := authorizeAccount(ctx, id); err != nil {
+		return nil, err
+	}
+
+	acc, err := r.server.accountClient.DeleteAccount(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return newAccount(*acc), nil
 }
 
 func (r *mutationResolver) CreateProduct(ctx context.Context, in ProductInput) (*Product, error) {
-	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
-	defer cancel()
+	price, err := in.Price.Money()
+	if err != nil {
+		return nil, errs.Invalid("invalid product", errs.Violation{
+			Field:       "price",
+			Description: err.Error(),
+		})
+	}
+
+	p, err := r.server.catalogClient.PostProduct(ctx, in.Name, in.Description, price)
+	if err != nil {
+		return nil, err
+	}
+
+	return newProduct(*p), nil
+}
+
+func (r *mutationResolver) UpdateProduct(ctx context.Context, id string, in ProductUpdateInput) (*Product, error) {
+	update := catalog.ProductUpdate{
+		Name:        in.Name,
+		Description: in.Description,
+	}
+	if in.Price != nil {
+		price, err := in.Price.Money()
+		if err != nil {
+			return nil, errs.Invalid("invalid product", errs.Violation{
+				Field:       "price",
+				Description: err.Error(),
+			})
+		}
+		update.Price = &price
+	}
 
-	p, err := r.server.catalogClient.PostProduct(ctx, in.Name, in.Description, in.Price)
+	p, err := r.server.catalogClient.UpdateProduct(ctx, id, update)
 	if err != nil {
-		log.Println(err)
 		return nil, err
 	}
 
-	return &Product{
-		ID:          p.ID,
-		Description: p.Description,
-		Name:        p.Name,
-		Price:       p.Price,
-	}, nil
+	return newProduct(*p), nil
+}
+
+func (r *mutationResolver) DeleteProduct(ctx context.Context, id string) (*Product, error) {
+	p, err := r.server.catalogClient.DeleteProduct(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return newProduct(*p), nil
 }
 
 func (r *mutationResolver) CreateOrder(ctx context.Context, in OrderInput) (*Order, error) {
-	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
-	defer cancel()
+	if err := authorizeAccount(ctx, in.AccountID); err != nil {
+		return nil, err
+	}
+
+	if err := validation.Validate(
+		validation.Each("products", in.Products, func(p *OrderProductInput) []validation.Check {
+			return []validation.Check{
+				validation.Field("quantity", p.Quantity, validation.Positive),
+			}
+		}),
+	); err != nil {
+		return nil, err
+	}
 
 	var products []order.OrderedProduct
 	for _, p := range in.Products {
-		if p.Quantity <= 0 {
-			return nil, ErrInvalidParameter
-		}
-
 		products = append(products, order.OrderedProduct{
 			ID:       p.ID,
 			Quantity: uint32(p.Quantity),
 		})
 	}
 
-	o, err := r.server.orderClient.PostOrder(ctx, in.AccountID, products)
+	o, err := r.server.orderClient.PostOrder(ctx, in.AccountID, stringValue(in.IdempotencyKey), products)
+	if err != nil {
+		return nil, err
+	}
+
+	return newOrder(*o), nil
+}
+
+func (r *mutationResolver) UpdateOrderStatus(ctx context.Context, id string, status OrderStatus) (*Order, error) {
+	if _, err := requireIdentity(ctx); err != nil {
+		return nil, err
+	}
+
+	o, err := r.server.orderClient.UpdateOrderStatus(ctx, id, status.ServiceStatus())
 	if err != nil {
-		log.Println(err)
 		return nil, err
 	}
 
-	return &Order{
-		ID:         o.ID,
-		CreatedAt:  o.CreatedAt,
-		TotalPrice: o.TotalPrice,
-	}, nil
+	return newOrder(*o), nil
 }
@@ -2,29 +2,39 @@ package main
 
 import (
 	"context"
-	"log"
-	"time"
+
+	"github.com/rajan-marasini/ecom-microservice/catalog"
 )
 
 type queryResolver struct {
 	server *Server
 }
 
-func (r *queryResolver) Accounts(ctx context.Context, pagination *PaginationInput, id *string) ([]*Account, error) {
-	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
-	defer cancel()
+func (r *queryResolver) Me(ctx context.Context) (*Account, error) {
+	acc, err := requireIdentity(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return newAccount(*acc), nil
+}
 
+func (r *queryResolver) Accounts(ctx context.Context, pagination *PaginationInput, id *string) ([]*Account, error) {
 	if id != nil {
+		if err := authorizeAccount(ctx, *id); err != nil {
+			return nil, err
+		}
+
 		r, err := r.server.accountClient.GetAccountByID(ctx, *id)
 		if err != nil {
-			log.Println(err)
 			return nil, err
 		}
 
-		return []*Account{{
-			ID:   r.ID,
-			Name: r.Name,
-		}}, nil
+		return []*Account{newAccount(*r)}, nil
+	}
+
+	if err := requireRole(ctx, RoleAdmin); err != nil {
+		return nil, err
 	}
 
 	skip, take := uint64(0), uint64(0)
@@ -33,39 +43,42 @@ func (r *queryResolver) Accounts(ctx context.Context, pagination *PaginationInpu
 	}
 	accountsList, err := r.server.accountClient.GetAccounts(ctx, skip, take)
 	if err != nil {
-		log.Println(err)
 		return nil, err
 	}
 
 	var accounts []*Account
 	for _, a := range accountsList {
-		account := &Account{
-			ID:   a.ID,
-			Name: a.Name,
-		}
-		accounts = append(accounts, account)
+		accounts = append(accounts, newAccount(a))
 	}
 
 	return accounts, nil
 }
 
-func (r *queryResolver) Products(ctx context.Context, pagination *PaginationInput, query *string, id *string) ([]*Product, error) {
-	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
-	defer cancel()
+func (r *queryResolver) Order(ctx context.Context, id string) (*Order, error) {
+	// Anonymous callers must not learn which order IDs exist.
+	if _, err := requireIdentity(ctx); err != nil {
+		return nil, err
+	}
 
+	o, err := r.server.orderClient.GetOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeAccount(ctx, o.AccountID); err != nil {
+		return nil, err
+	}
+
+	return newOrder(*o), nil
+}
+
+func (r *queryResolver) Products(ctx context.Context, pagination *PaginationInput, query *string, id *string, sort *ProductSort) ([]*Product, error) {
 	if id != nil {
 		r, err := r.server.catalogClient.GetProduct(ctx, *id)
 		if err != nil {
-			log.Println(err)
 			return nil, err
 		}
 
-		return []*Product{{
-			ID:          r.ID,
-			Name:        r.Name,
-			Description: r.Description,
-			Price:       r.Price,
-		}}, nil
+		return []*Product{newProduct(*r)}, nil
 	}
 
 	skip, take := uint64(0), uint64(0)
@@ -78,20 +91,19 @@ func (r *queryResolver) Products(ctx context.Context, pagination *PaginationInpu
 		q = *query
 	}
 
-	productList, err := r.server.catalogClient.GetProducts(ctx, skip, take, nil, q)
+	productSort := catalog.SortNewest
+	if sort != nil {
+		productSort = sort.CatalogSort()
+	}
+
+	productList, err := r.server.catalogClient.GetProducts(ctx, skip, take, nil, q, productSort)
 	if err != nil {
-		log.Println(err)
 		return nil, err
 	}
 
 	var products []*Product
 	for _, a := range productList {
-		products = append(products, &Product{
-			ID:          a.ID,
-			Name:        a.Name,
-			Description: a.Description,
-			Price:       a.Price,
-		})
+		products = append(products, newProduct(a))
 	}
 
 	return products, nil
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/rajan-marasini/ecom-microservice/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// resolverTracing is a gqlgen extension that wraps every field backed by a
+// resolver in a span, so the gRPC calls it makes nest under it.
+type resolverTracing struct{}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.FieldInterceptor
+} = resolverTracing{}
+
+func (resolverTracing) ExtensionName() string {
+	return "ResolverTracing"
+}
+
+func (resolverTracing) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (resolverTracing) InterceptField(ctx context.Context, next graphql.Resolver) (any, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if !fc.IsResolver {
+		return next(ctx)
+	}
+
+	ctx, span := tracing.Tracer().Start(ctx, fc.Object+"."+fc.Field.Name)
+	defer span.End()
+	span.SetAttributes(attribute.String("graphql.field.path", fc.Path().String()))
+
+	res, err := next(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return res, err
+}
@@ -0,0 +1,53 @@
+package grpcclient
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/sony/gobreaker/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newBreaker returns an interceptor that stops calling service while it keeps
+// failing, so callers fail fast instead of piling up on their deadlines.
+// Only unavailability and timeouts count as failures; errors about the
+// request itself, such as NOT_FOUND, show that the service is up. Health
+// checks bypass the breaker so they always report the actual state.
+func newBreaker(service string, c Config) grpc.UnaryClientInterceptor {
+	cb := gobreaker.NewCircuitBreaker[struct{}](gobreaker.Settings{
+		Name:    service,
+		Timeout: c.BreakerTimeout,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return c.BreakerFailures > 0 && counts.ConsecutiveFailures >= c.BreakerFailures
+		},
+		IsSuccessful: func(err error) bool {
+			switch status.Code(err) {
+			case codes.Unavailable, codes.DeadlineExceeded:
+				return false
+			default:
+				return true
+			}
+		},
+		OnStateChange: func(name string, from, to gobreaker.State) {
+			slog.Warn("circuit breaker state changed", "target", name, "from", from.String(), "to", to.String())
+		},
+	})
+
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if strings.HasPrefix(method, "/grpc.health.v1.Health/") {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		_, err := cb.Execute(func() (struct{}, error) {
+			return struct{}{}, invoker(ctx, method, req, reply, cc, opts...)
+		})
+		if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
+			return status.Errorf(codes.Unavailable, "%s: %v", service, err)
+		}
+		return err
+	}
+}
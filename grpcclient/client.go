@@ -0,0 +1,155 @@
+// Package grpcclient dials the services with the options every client
+// shares: mutual TLS, tracing, metrics and logging, a default deadline per
+// call, retries of idempotent reads, a circuit breaker and load balancing
+// across the addresses of the service.
+package grpcclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/rajan-marasini/ecom-microservice/logging"
+	"github.com/rajan-marasini/ecom-microservice/metrics"
+	"github.com/rajan-marasini/ecom-microservice/mtls"
+	"github.com/rajan-marasini/ecom-microservice/tracing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+)
+
+// Config tunes how clients call a service.
+type Config struct {
+	// Timeout is the deadline of calls made with a context that has none.
+	Timeout time.Duration `envconfig:"TIMEOUT" default:"3s"`
+	// MaxAttempts bounds how often an idempotent read is tried while the
+	// service is unavailable. gRPC allows at most 5; 1 disables retries.
+	MaxAttempts int `envconfig:"MAX_ATTEMPTS" default:"3"`
+	// BreakerFailures consecutive failures open the circuit breaker, which
+	// then fails calls at once for BreakerTimeout before letting one through
+	// to probe the service.
+	BreakerFailures uint32        `envconfig:"BREAKER_FAILURES" default:"5"`
+	BreakerTimeout  time.Duration `envconfig:"BREAKER_TIMEOUT" default:"10s"`
+}
+
+// Options configure the clients of the services.
+type Options struct {
+	Config
+	// Credentials enable mutual TLS; nil means plaintext.
+	Credentials *mtls.Credentials
+}
+
+// Dial connects to service, the full name of a gRPC service such as
+// pb.AccountService, at target. reads names the methods of service that are
+// safe to retry.
+//
+// target is a host and port, resolved through DNS so that calls are balanced
+// across all of its addresses, or a comma-separated list of them, e.g.
+// "account-1:8080,account-2:8080". Any other gRPC target, such as
+// "dns:///account:8080", is used as is.
+func Dial(target, service string, opts Options, reads ...string) (*grpc.ClientConn, error) {
+	serviceConfig, err := newServiceConfig(service, opts.MaxAttempts, reads)
+	if err != nil {
+		return nil, err
+	}
+
+	dialOptions := []grpc.DialOption{
+		opts.Credentials.DialOption(),
+		tracing.DialOption(),
+		grpc.WithDefaultServiceConfig(serviceConfig),
+		grpc.WithChainUnaryInterceptor(
+			withDefaultTimeout(opts.Timeout),
+			metrics.UnaryClientInterceptor,
+			logging.UnaryClientInterceptor,
+			newBreaker(service, opts.Config),
+		),
+	}
+
+	if strings.Contains(target, ",") {
+		r := manual.NewBuilderWithScheme("static")
+		state := resolver.State{}
+		for _, addr := range strings.Split(target, ",") {
+			addr = strings.TrimSpace(addr)
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, fmt.Errorf("grpcclient: invalid address %q in %q: %w", addr, target, err)
+			}
+			// The target names the service rather than a host, so each
+			// address carries the host its certificate must be valid for.
+			state.Addresses = append(state.Addresses, resolver.Address{Addr: addr, ServerName: host})
+		}
+		r.InitialState(state)
+		target = r.Scheme() + ":///" + service
+		dialOptions = append(dialOptions, grpc.WithResolvers(r))
+	}
+
+	return grpc.NewClient(target, dialOptions...)
+}
+
+// withDefaultTimeout bounds calls made with a context without a deadline.
+// Calls made on behalf of a request keep the deadline of that request.
+func withDefaultTimeout(timeout time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if _, ok := ctx.Deadline(); !ok && timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+type serviceConfig struct {
+	LoadBalancingConfig []map[string]struct{} `json:"loadBalancingConfig"`
+	MethodConfig        []methodConfig        `json:"methodConfig,omitempty"`
+}
+
+type methodConfig struct {
+	Name        []methodName `json:"name"`
+	RetryPolicy retryPolicy  `json:"retryPolicy"`
+}
+
+type methodName struct {
+	Service string `json:"service"`
+	Method  string `json:"method"`
+}
+
+type retryPolicy struct {
+	MaxAttempts          int      `json:"maxAttempts"`
+	InitialBackoff       string   `json:"initialBackoff"`
+	MaxBackoff           string   `json:"maxBackoff"`
+	BackoffMultiplier    float64  `json:"backoffMultiplier"`
+	RetryableStatusCodes []string `json:"retryableStatusCodes"`
+}
+
+// newServiceConfig balances calls round robin across the addresses of the
+// service and retries reads that fail with UNAVAILABLE, backing off
+// exponentially.
+func newServiceConfig(service string, maxAttempts int, reads []string) (string, error) {
+	c := serviceConfig{
+		LoadBalancingConfig: []map[string]struct{}{{"round_robin": {}}},
+	}
+
+	if maxAttempts > 1 && len(reads) != 0 {
+		names := []methodName{}
+		for _, method := range reads {
+			names = append(names, methodName{service, method})
+		}
+		c.MethodConfig = append(c.MethodConfig, methodConfig{
+			Name: names,
+			RetryPolicy: retryPolicy{
+				MaxAttempts:          maxAttempts,
+				InitialBackoff:       "0.1s",
+				MaxBackoff:           "1s",
+				BackoffMultiplier:    2,
+				RetryableStatusCodes: []string{"UNAVAILABLE"},
+			},
+		})
+	}
+
+	b, err := json.Marshal(c)
+	return string(b), err
+}
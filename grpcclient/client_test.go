@@ -0,0 +1,130 @@
+package grpcclient
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rajan-marasini/ecom-microservice/mtls"
+	"github.com/rajan-marasini/ecom-microservice/mtls/mtlstest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+)
+
+// newCredentials issues a certificate for hosts, signed by ca, and loads it.
+func newCredentials(t *testing.T, ca *mtlstest.CA, name string, hosts ...string) *mtls.Credentials {
+	t.Helper()
+
+	c, err := ca.WriteFiles(t.TempDir(), name, hosts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds, err := mtls.Load(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return creds
+}
+
+func TestDialAddressListWithMutualTLS(t *testing.T) {
+	ca, err := mtlstest.NewCA()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Record the authority of every call, which is the name the client
+	// verified the server certificate against.
+	var mu sync.Mutex
+	authorities := map[string]bool{}
+	recordAuthority := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if md, ok := metadata.FromIncomingContext(ctx); ok && len(md[":authority"]) != 0 {
+			mu.Lock()
+			authorities[md[":authority"][0]] = true
+			mu.Unlock()
+		}
+		return handler(ctx, req)
+	}
+
+	list, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serv := grpc.NewServer(
+		newCredentials(t, ca, "server", "localhost", "127.0.0.1").ServerOption(),
+		grpc.UnaryInterceptor(recordAuthority),
+	)
+	healthpb.RegisterHealthServer(serv, health.NewServer())
+	go serv.Serve(list)
+	t.Cleanup(serv.Stop)
+
+	port := strconv.Itoa(list.Addr().(*net.TCPAddr).Port)
+	target := net.JoinHostPort("localhost", port) + "," + net.JoinHostPort("127.0.0.1", port)
+	conn, err := Dial(target, "grpc.health.v1.Health", Options{
+		Config:      Config{Timeout: time.Second},
+		Credentials: newCredentials(t, ca, "client", "client"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	// Calls are balanced round robin once both addresses are connected.
+	client := healthpb.NewHealthClient(conn)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+			t.Fatal(err)
+		}
+
+		mu.Lock()
+		done := authorities["localhost"] && authorities["127.0.0.1"]
+		mu.Unlock()
+		if done {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("calls reached the server as %v, want localhost and 127.0.0.1", authorities)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDialAddressListVerifiesEachHost(t *testing.T) {
+	ca, err := mtlstest.NewCA()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each server's certificate names the host the other one is listed
+	// with, so every address fails unless all share one name.
+	serve := func(host string) string {
+		list, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		serv := grpc.NewServer(newCredentials(t, ca, "server-"+host, host).ServerOption())
+		healthpb.RegisterHealthServer(serv, health.NewServer())
+		go serv.Serve(list)
+		t.Cleanup(serv.Stop)
+		return strconv.Itoa(list.Addr().(*net.TCPAddr).Port)
+	}
+	target := net.JoinHostPort("localhost", serve("127.0.0.1")) + "," + net.JoinHostPort("127.0.0.1", serve("localhost"))
+
+	conn, err := Dial(target, "grpc.health.v1.Health", Options{
+		Config:      Config{Timeout: time.Second},
+		Credentials: newCredentials(t, ca, "client", "client"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	if _, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{}); err == nil {
+		t.Error("servers with certificates for other hosts were accepted")
+	}
+}
@@ -0,0 +1,65 @@
+package grpcserver
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	healthInterval = 5 * time.Second
+	healthTimeout  = 2 * time.Second
+)
+
+// Check reports an error when something the server depends on is unusable.
+type Check func(ctx context.Context) error
+
+// RegisterHealth registers the standard gRPC health service on serv. The
+// server as a whole and service are SERVING while all checks pass. Checks run
+// every few seconds until ctx is done; from then on the status is
+// NOT_SERVING, so clients move away while the server shuts down.
+func RegisterHealth(ctx context.Context, serv *grpc.Server, service string, checks ...Check) {
+	hs := health.NewServer()
+	healthpb.RegisterHealthServer(serv, hs)
+
+	current := healthpb.HealthCheckResponse_UNKNOWN
+	update := func() {
+		status := healthpb.HealthCheckResponse_SERVING
+		for _, check := range checks {
+			checkCtx, cancel := context.WithTimeout(ctx, healthTimeout)
+			err := check(checkCtx)
+			cancel()
+			if err != nil {
+				if current != healthpb.HealthCheckResponse_NOT_SERVING {
+					slog.ErrorContext(ctx, "health check failed", "service", service, "error", err)
+				}
+				status = healthpb.HealthCheckResponse_NOT_SERVING
+				break
+			}
+		}
+		if status != current {
+			current = status
+			hs.SetServingStatus("", status)
+			hs.SetServingStatus(service, status)
+		}
+	}
+
+	update()
+	go func() {
+		ticker := time.NewTicker(healthInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				hs.Shutdown()
+				return
+			case <-ticker.C:
+				update()
+			}
+		}
+	}()
+}
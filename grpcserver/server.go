@@ -0,0 +1,77 @@
+// Package grpcserver runs a gRPC server in the background and shuts it down
+// gracefully.
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"google.golang.org/grpc"
+)
+
+// Server is a running gRPC server.
+type Server struct {
+	serv    *grpc.Server
+	closers []func()
+	done    chan struct{}
+	err     error
+}
+
+// Start listens on port and serves serv in the background. closers run
+// after the server has stopped, in order, to release what its handlers use.
+func Start(ctx context.Context, serv *grpc.Server, port int, closers ...func()) (*Server, error) {
+	var lc net.ListenConfig
+	list, err := lc.Listen(ctx, "tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		serv:    serv,
+		closers: closers,
+		done:    make(chan struct{}),
+	}
+	go func() {
+		s.err = serv.Serve(list)
+		close(s.done)
+	}()
+	return s, nil
+}
+
+// Done is closed when the server stops serving, either after Shutdown or
+// because serving failed.
+func (s *Server) Done() <-chan struct{} {
+	return s.done
+}
+
+// Shutdown stops accepting connections and waits for in-flight RPCs to
+// finish. If ctx ends first, the remaining RPCs are cancelled. It returns the
+// error serving failed with, if any, or ctx's error when it had to cut RPCs
+// short.
+func (s *Server) Shutdown(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		s.serv.GracefulStop()
+		close(stopped)
+	}()
+
+	var err error
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.serv.Stop()
+		<-stopped
+		err = ctx.Err()
+	}
+	<-s.done
+
+	for _, c := range s.closers {
+		c()
+	}
+
+	if s.err != nil {
+		return s.err
+	}
+	return err
+}
@@ -0,0 +1,66 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/rajan-marasini/ecom-microservice/errs"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// requestIDMetadataKey carries the request ID between services.
+const requestIDMetadataKey = "x-request-id"
+
+// UnaryServerInterceptor continues the caller's request ID, or starts one,
+// and logs every RPC except health checks once it has been handled. Put it
+// after interceptors that turn errors into status codes, so it sees the full
+// error.
+func UnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	id := NewRequestID()
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(requestIDMetadataKey); len(v) != 0 && v[0] != "" {
+			id = v[0]
+		}
+	}
+	ctx = WithRequestID(ctx, id)
+
+	if strings.HasPrefix(info.FullMethod, "/grpc.health.v1.Health/") {
+		return handler(ctx, req)
+	}
+
+	start := time.Now()
+	resp, err := handler(ctx, req)
+
+	args := []any{"method", info.FullMethod, "duration", time.Since(start)}
+	if err == nil {
+		slog.InfoContext(ctx, "rpc handled", args...)
+		return resp, nil
+	}
+
+	args = append(args, "code", errs.KindOf(err).String(), "error", err)
+	slog.Log(ctx, LevelOf(err), "rpc failed", args...)
+	return resp, err
+}
+
+// UnaryClientInterceptor passes the request ID of the context on to the
+// service being called.
+func UnaryClientInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if id := RequestID(ctx); id != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, requestIDMetadataKey, id)
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// LevelOf returns the level to log err at: warning for errors caused by the
+// caller, and error for the rest, which need looking into.
+func LevelOf(err error) slog.Level {
+	switch errs.KindOf(err) {
+	case errs.Internal, errs.Unavailable:
+		return slog.LevelError
+	default:
+		return slog.LevelWarn
+	}
+}
@@ -0,0 +1,100 @@
+// Package logging sets up structured logging with log/slog and carries a
+// request ID from the gateway through every service, so the log lines of one
+// request can be found together.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/segmentio/ksuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Config selects the lowest level logged (debug, info, warn or error) and
+// the output format (json or text).
+type Config struct {
+	Level  string `envconfig:"LEVEL" default:"info"`
+	Format string `envconfig:"FORMAT" default:"json"`
+}
+
+// Init makes a logger configured by c the default for both log/slog and the
+// log package. Every line carries the service name, and the request and
+// trace IDs of the context it was logged with.
+func Init(c Config, service string) error {
+	h, err := newHandler(c, os.Stderr)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(slog.New(h).With("service", service))
+	return nil
+}
+
+func newHandler(c Config, w io.Writer) (slog.Handler, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Level)); err != nil {
+		return nil, fmt.Errorf("logging: invalid level %q", c.Level)
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	switch strings.ToLower(c.Format) {
+	case "json":
+		return contextHandler{slog.NewJSONHandler(w, opts)}, nil
+	case "text":
+		return contextHandler{slog.NewTextHandler(w, opts)}, nil
+	default:
+		return nil, fmt.Errorf("logging: invalid format %q", c.Format)
+	}
+}
+
+// Fatal logs msg at error level and exits.
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+type requestIDKey struct{}
+
+// NewRequestID returns a new, unique request ID.
+func NewRequestID() string {
+	return ksuid.New().String()
+}
+
+// WithRequestID returns a copy of ctx carrying the request ID id.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, or "" if there is none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// contextHandler adds the request and trace IDs of the context to each
+// record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
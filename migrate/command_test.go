@@ -0,0 +1,43 @@
+package migrate
+
+import (
+	"context"
+	"io"
+	"testing"
+)
+
+// The cases below fail before a connection is needed, so the URL points
+// nowhere.
+func TestRunRejectsArguments(t *testing.T) {
+	fsys := scripts("0001_create_accounts.up.sql")
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{name: "no command", wantErr: usage},
+		{name: "unknown command", args: []string{"redo"}, wantErr: usage},
+		{name: "steps not a number", args: []string{"down", "two"}, wantErr: `invalid steps "two"`},
+		{name: "zero steps", args: []string{"down", "0"}, wantErr: `invalid steps "0"`},
+		{name: "negative steps", args: []string{"down", "-1"}, wantErr: `invalid steps "-1"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Run(context.Background(), "postgres://127.0.0.1:1/none", fsys, tt.args, io.Discard)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunRejectsInvalidMigrations(t *testing.T) {
+	fsys := scripts("0001_create_accounts.down.sql")
+
+	err := Run(context.Background(), "postgres://127.0.0.1:1/none", fsys, []string{"up"}, io.Discard)
+	if want := "migration 0001_create_accounts has no up script"; err == nil || err.Error() != want {
+		t.Errorf("err = %v, want %q", err, want)
+	}
+}
@@ -0,0 +1,129 @@
+package migrate
+
+import (
+	"slices"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// scripts builds a migration filesystem from file names, using each name as
+// the body of its file.
+func scripts(names ...string) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for _, name := range names {
+		fsys[name] = &fstest.MapFile{Data: []byte(name)}
+	}
+	return fsys
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load(scripts(
+		"0010_add_orders.up.sql",
+		"0002_add_index.up.sql",
+		"0002_add_index.down.sql",
+		"0001_create_accounts.up.sql",
+		"0001_create_accounts.down.sql",
+		"README.md",
+		"0003_notes.sql",
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Migration{
+		{Version: 1, Name: "create_accounts", Up: "0001_create_accounts.up.sql", Down: "0001_create_accounts.down.sql"},
+		{Version: 2, Name: "add_index", Up: "0002_add_index.up.sql", Down: "0002_add_index.down.sql"},
+		{Version: 10, Name: "add_orders", Up: "0010_add_orders.up.sql"},
+	}
+	if !slices.Equal(migrations, want) {
+		t.Errorf("migrations = %+v, want %+v", migrations, want)
+	}
+}
+
+func TestLoadRejectsFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []string
+		wantErr string
+	}{
+		{
+			name:    "conflicting names",
+			files:   []string{"0001_create_accounts.up.sql", "0001_create_users.down.sql"},
+			wantErr: `migration 1 has conflicting names "create_accounts" and "create_users"`,
+		},
+		{
+			name:    "duplicate version",
+			files:   []string{"0001_create_accounts.up.sql", "1_create_accounts.up.sql"},
+			wantErr: "migration 0001_create_accounts has more than one up script",
+		},
+		{
+			name:    "missing up script",
+			files:   []string{"0001_create_accounts.up.sql", "0002_add_index.down.sql"},
+			wantErr: "migration 0002_add_index has no up script",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(scripts(tt.files...))
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestToRevert(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Name: "create_accounts", Up: "up", Down: "down"},
+		{Version: 2, Name: "add_index", Up: "up"},
+		{Version: 3, Name: "add_orders", Up: "up", Down: "down"},
+		{Version: 4, Name: "add_payments", Up: "up", Down: "down"},
+	}
+	applied := func(versions ...int64) map[int64]time.Time {
+		m := map[int64]time.Time{}
+		for _, v := range versions {
+			m[v] = time.Now()
+		}
+		return m
+	}
+
+	tests := []struct {
+		name     string
+		versions map[int64]time.Time
+		steps    int
+		want     []int64
+		wantErr  string
+	}{
+		{name: "latest", versions: applied(1, 2, 3, 4), steps: 1, want: []int64{4}},
+		{name: "newest first", versions: applied(1, 2, 3, 4), steps: 2, want: []int64{4, 3}},
+		{name: "skips pending", versions: applied(1, 3), steps: 2, want: []int64{3, 1}},
+		{name: "more steps than applied", versions: applied(3, 4), steps: 5, want: []int64{4, 3}},
+		{name: "nothing applied", versions: applied(), steps: 1},
+		{name: "irreversible", versions: applied(1, 2, 3, 4), steps: 3, wantErr: "migration 0002_add_index cannot be reverted"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			picked, err := toRevert(migrations, tt.versions, tt.steps)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var versions []int64
+			for _, m := range picked {
+				versions = append(versions, m.Version)
+			}
+			if !slices.Equal(versions, tt.want) {
+				t.Errorf("versions = %v, want %v", versions, tt.want)
+			}
+		})
+	}
+}
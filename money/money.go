@@ -0,0 +1,186 @@
+//go:generate protoc -I .. --go_out=.. --go_opt=module=github.com/rajan-marasini/ecom-microservice ../money/money.proto
+
+// Package money represents amounts as integer minor units of an ISO 4217
+// currency so prices and totals add up exactly.
+package money
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/rajan-marasini/ecom-microservice/errs"
+	"github.com/rajan-marasini/ecom-microservice/money/pb"
+)
+
+const DefaultCurrency = "USD"
+
+// exponents lists the supported currencies and the number of digits after
+// the decimal point in their minor unit.
+var exponents = map[string]int{
+	"AUD": 2,
+	"BHD": 3,
+	"CAD": 2,
+	"CHF": 2,
+	"CNY": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"INR": 2,
+	"JPY": 0,
+	"KRW": 0,
+	"KWD": 3,
+	"NPR": 2,
+	"USD": 2,
+}
+
+// Money is an amount in the minor unit of Currency, e.g. cents for USD.
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// ValidCurrency reports whether currency is a supported ISO 4217 code.
+func ValidCurrency(currency string) bool {
+	_, ok := exponents[currency]
+	return ok
+}
+
+// Exponent returns the number of minor unit digits of currency.
+func Exponent(currency string) int {
+	if e, ok := exponents[currency]; ok {
+		return e
+	}
+	return 2
+}
+
+// Parse reads a decimal amount such as "19.99" in currency. It rejects
+// amounts with more fractional digits than the currency allows rather than
+// rounding them.
+func Parse(amount, currency string) (Money, error) {
+	if !ValidCurrency(currency) {
+		return Money{}, errs.New(errs.InvalidArgument, "unsupported currency %q", currency)
+	}
+	exp := Exponent(currency)
+
+	s := strings.TrimSpace(amount)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" || len(frac) > exp || !digits(whole) || !digits(frac) {
+		return Money{}, errs.New(errs.InvalidArgument, "invalid %s amount %q", currency, amount)
+	}
+	frac += strings.Repeat("0", exp-len(frac))
+
+	minor, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return Money{}, errs.New(errs.InvalidArgument, "invalid %s amount %q", currency, amount)
+	}
+	if negative {
+		minor = -minor
+	}
+
+	return Money{Amount: minor, Currency: currency}, nil
+}
+
+func digits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Decimal formats the amount in major units, e.g. "19.99".
+func (m Money) Decimal() string {
+	exp := Exponent(m.Currency)
+
+	sign := ""
+	amount := uint64(m.Amount)
+	if m.Amount < 0 {
+		sign = "-"
+		amount = uint64(-m.Amount)
+	}
+
+	s := strconv.FormatUint(amount, 10)
+	if exp == 0 {
+		return sign + s
+	}
+	if len(s) <= exp {
+		s = strings.Repeat("0", exp-len(s)+1) + s
+	}
+
+	return sign + s[:len(s)-exp] + "." + s[len(s)-exp:]
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// Add returns m + o. Both amounts must be in the same currency.
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, errs.New(errs.InvalidArgument, "cannot add %s to %s", o.Currency, m.Currency)
+	}
+	if (o.Amount > 0 && m.Amount > math.MaxInt64-o.Amount) || (o.Amount < 0 && m.Amount < math.MinInt64-o.Amount) {
+		return Money{}, errs.New(errs.InvalidArgument, "amount overflow")
+	}
+
+	return Money{Amount: m.Amount + o.Amount, Currency: m.Currency}, nil
+}
+
+// Mul returns m * n.
+func (m Money) Mul(n int64) (Money, error) {
+	if m.Amount != 0 && n != 0 {
+		product := m.Amount * n
+		if product/n != m.Amount || (m.Amount == -1 && n == math.MinInt64) || (n == -1 && m.Amount == math.MinInt64) {
+			return Money{}, errs.New(errs.InvalidArgument, "amount overflow")
+		}
+	}
+
+	return Money{Amount: m.Amount * n, Currency: m.Currency}, nil
+}
+
+// Validate describes what is wrong with m as a price, or returns an empty
+// string. It is a validation.Rule.
+func Validate(m Money) string {
+	switch {
+	case m.Currency == "":
+		return "currency is required"
+	case !ValidCurrency(m.Currency):
+		return fmt.Sprintf("unsupported currency %q", m.Currency)
+	case m.IsNegative():
+		return "must not be negative"
+	default:
+		return ""
+	}
+}
+
+func ToProto(m Money) *pb.Money {
+	return &pb.Money{Amount: m.Amount, Currency: m.Currency}
+}
+
+func FromProto(m *pb.Money) Money {
+	if m == nil {
+		return Money{}
+	}
+	return Money{Amount: m.Amount, Currency: m.Currency}
+}
+
+// FromFloat converts a floating point amount in major units, rounding to the
+// nearest minor unit. It exists for reading prices stored before amounts were
+// kept exactly and should not be used for new data.
+func FromFloat(amount float64, currency string) Money {
+	scale := math.Pow10(Exponent(currency))
+	return Money{Amount: int64(math.Round(amount * scale)), Currency: currency}
+}
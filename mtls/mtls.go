@@ -0,0 +1,217 @@
+// Package mtls configures mutual TLS between the services. Certificates are
+// read from files and reloaded when the files change, so they can be rotated
+// without a restart.
+package mtls
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Config names the PEM files of a service's certificate, its key and the CA
+// that signs the certificates of its peers. TLS is off when all are empty.
+type Config struct {
+	CertFile string `envconfig:"CERT_FILE"`
+	KeyFile  string `envconfig:"KEY_FILE"`
+	CAFile   string `envconfig:"CA_FILE"`
+}
+
+func (c Config) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != "" || c.CAFile != ""
+}
+
+// Credentials provide a service's certificate both when serving and when
+// calling other services, and require the peer to present one signed by the
+// CA. A nil *Credentials means plaintext.
+type Credentials struct {
+	config Config
+
+	mu          sync.Mutex
+	modTime     time.Time
+	certificate *tls.Certificate
+	pool        *x509.CertPool
+}
+
+// Load reads the files in c. It returns nil credentials when TLS is not
+// configured.
+func Load(c Config) (*Credentials, error) {
+	if !c.Enabled() {
+		return nil, nil
+	}
+	if c.CertFile == "" || c.KeyFile == "" || c.CAFile == "" {
+		return nil, errors.New("mtls: cert, key and CA files must all be set")
+	}
+
+	creds := &Credentials{config: c}
+	if _, _, err := creds.load(); err != nil {
+		return nil, err
+	}
+	return creds, nil
+}
+
+// ServerOption configures a gRPC server to require client certificates.
+func (c *Credentials) ServerOption() grpc.ServerOption {
+	if c == nil {
+		return grpc.Creds(insecure.NewCredentials())
+	}
+	return grpc.Creds(credentials.NewTLS(c.ServerConfig()))
+}
+
+// DialOption configures a gRPC client to present the certificate and verify
+// the server against the CA.
+func (c *Credentials) DialOption() grpc.DialOption {
+	if c == nil {
+		return grpc.WithTransportCredentials(insecure.NewCredentials())
+	}
+	return grpc.WithTransportCredentials(clientCredentials{credentials.NewTLS(c.ClientConfig("")), c})
+}
+
+// clientCredentials build the TLS config of every connection for the host it
+// is made to. A shared config can't check IP addresses: Go sends no server
+// name for them, so the handshake doesn't say which one was dialed.
+type clientCredentials struct {
+	credentials.TransportCredentials
+	creds *Credentials
+}
+
+func (t clientCredentials) ClientHandshake(ctx context.Context, authority string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	host, _, err := net.SplitHostPort(authority)
+	if err != nil {
+		host = authority
+	}
+	return credentials.NewTLS(t.creds.ClientConfig(host)).ClientHandshake(ctx, authority, conn)
+}
+
+func (t clientCredentials) Clone() credentials.TransportCredentials {
+	return clientCredentials{t.TransportCredentials.Clone(), t.creds}
+}
+
+func (c *Credentials) ServerConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			certificate, pool, err := c.load()
+			if err != nil {
+				return nil, err
+			}
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*certificate},
+				ClientAuth:   tls.RequireAndVerifyClientCert,
+				ClientCAs:    pool,
+			}, nil
+		},
+	}
+}
+
+// ClientConfig verifies that the server is serverName, a host name or an IP
+// address. It checks the certificate itself rather than through RootCAs,
+// which a tls.Config cannot swap after use, so a reloaded CA applies to new
+// connections.
+func (c *Credentials) ClientConfig(serverName string) *tls.Config {
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			certificate, _, err := c.load()
+			return certificate, err
+		},
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			_, pool, err := c.load()
+			if err != nil {
+				return err
+			}
+			if serverName == "" {
+				return errors.New("mtls: no server name to verify")
+			}
+			if len(cs.PeerCertificates) == 0 {
+				return errors.New("mtls: server presented no certificate")
+			}
+			intermediates := x509.NewCertPool()
+			for _, cert := range cs.PeerCertificates[1:] {
+				intermediates.AddCert(cert)
+			}
+			// DNSName also matches IP addresses against the certificate's
+			// IP SANs.
+			_, err = cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+				DNSName:       serverName,
+				Roots:         pool,
+				Intermediates: intermediates,
+			})
+			return err
+		},
+	}
+}
+
+// load returns the current certificate and CA pool, rereading the files when
+// any of them changed since the last call. If the new files are invalid, for
+// instance because they are only partly written, the previous ones are kept.
+func (c *Credentials) load() (*tls.Certificate, *x509.CertPool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	modTime, err := c.latestModTime()
+	if err != nil || modTime.Equal(c.modTime) {
+		if c.certificate == nil {
+			return nil, nil, err
+		}
+		return c.certificate, c.pool, nil
+	}
+
+	certificate, pool, err := c.read()
+	if err != nil {
+		if c.certificate == nil {
+			return nil, nil, err
+		}
+		slog.Warn("mtls: keeping previous certificates", "error", err)
+		return c.certificate, c.pool, nil
+	}
+
+	c.modTime, c.certificate, c.pool = modTime, certificate, pool
+	return certificate, pool, nil
+}
+
+func (c *Credentials) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, name := range []string{c.config.CertFile, c.config.KeyFile, c.config.CAFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+func (c *Credentials) read() (*tls.Certificate, *x509.CertPool, error) {
+	certificate, err := tls.LoadX509KeyPair(c.config.CertFile, c.config.KeyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ca, err := os.ReadFile(c.config.CAFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, nil, fmt.Errorf("mtls: no certificates in %s", c.config.CAFile)
+	}
+
+	return &certificate, pool, nil
+}
@@ -0,0 +1,110 @@
+package mtls_test
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rajan-marasini/ecom-microservice/mtls"
+	"github.com/rajan-marasini/ecom-microservice/mtls/mtlstest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// newCredentials issues a certificate for hosts, signed by ca, and loads it.
+func newCredentials(t *testing.T, ca *mtlstest.CA, name string, hosts ...string) *mtls.Credentials {
+	t.Helper()
+
+	c, err := ca.WriteFiles(t.TempDir(), name, hosts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds, err := mtls.Load(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return creds
+}
+
+// serve runs a health server with creds on a local port and returns the port.
+func serve(t *testing.T, creds *mtls.Credentials) string {
+	t.Helper()
+
+	list, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serv := grpc.NewServer(creds.ServerOption())
+	healthpb.RegisterHealthServer(serv, health.NewServer())
+	go serv.Serve(list)
+	t.Cleanup(serv.Stop)
+
+	return strconv.Itoa(list.Addr().(*net.TCPAddr).Port)
+}
+
+func TestServerVerification(t *testing.T) {
+	ca, err := mtlstest.NewCA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newCredentials(t, ca, "client", "client")
+
+	tests := []struct {
+		name        string
+		serverHosts []string
+		host        string
+		wantErr     bool
+	}{
+		{name: "matching name", serverHosts: []string{"localhost"}, host: "localhost"},
+		{name: "matching IP", serverHosts: []string{"127.0.0.1"}, host: "127.0.0.1"},
+		{name: "wrong name", serverHosts: []string{"some-other-host"}, host: "localhost", wantErr: true},
+		{name: "name for an IP", serverHosts: []string{"some-other-host"}, host: "127.0.0.1", wantErr: true},
+		{name: "wrong IP", serverHosts: []string{"10.0.0.1"}, host: "127.0.0.1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port := serve(t, newCredentials(t, ca, "server", tt.serverHosts...))
+
+			conn, err := grpc.NewClient(net.JoinHostPort(tt.host, port), client.DialOption())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Errorf("err = %v, want error: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestServerRejectsUnknownClient(t *testing.T) {
+	ca, err := mtlstest.NewCA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := mtlstest.NewCA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := serve(t, newCredentials(t, ca, "server", "localhost"))
+
+	conn, err := grpc.NewClient(net.JoinHostPort("localhost", port), newCredentials(t, other, "client", "client").DialOption())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}); err == nil {
+		t.Error("client with a certificate from another CA was accepted")
+	}
+}
@@ -4,8 +4,12 @@ import (
 	"context"
 	"time"
 
+	"github.com/rajan-marasini/ecom-microservice/errs"
+	"github.com/rajan-marasini/ecom-microservice/grpcclient"
+	"github.com/rajan-marasini/ecom-microservice/money"
 	"github.com/rajan-marasini/ecom-microservice/order/pb"
 	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 type Client struct {
@@ -13,8 +17,11 @@ type Client struct {
 	service pb.OrderServiceClient
 }
 
-func NewClient(url string) (*Client, error) {
-	conn, err := grpc.NewClient(url, grpc.WithInsecure())
+// NewClient connects to the service at url, which may list several
+// addresses; see grpcclient.Dial. Reads are retried while the service is
+// unavailable.
+func NewClient(url string, opts grpcclient.Options) (*Client, error) {
+	conn, err := grpcclient.Dial(url, pb.OrderService_ServiceDesc.ServiceName, opts, "GetOrder", "GetOrdersForAccount")
 	if err != nil {
 		return nil, err
 	}
@@ -31,7 +38,21 @@ func (c *Client) Close() {
 	c.conn.Close()
 }
 
-func (c *Client) PostOrder(ctx context.Context, accountID string, products []OrderedProduct) (*Order, error) {
+// Health reports an error unless the order service is serving.
+func (c *Client) Health(ctx context.Context) error {
+	res, err := healthpb.NewHealthClient(c.conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		return errs.FromGRPC(err)
+	}
+	if res.Status != healthpb.HealthCheckResponse_SERVING {
+		return errs.New(errs.Unavailable, "order service is %s", res.Status)
+	}
+	return nil
+}
+
+// PostOrder places an order. Retrying it with the same non-empty
+// idempotencyKey returns the order placed first.
+func (c *Client) PostOrder(ctx context.Context, accountID, idempotencyKey string, products []OrderedProduct) (*Order, error) {
 	protoProducts := []*pb.PostOrderRequest_OrderProduct{}
 
 	for _, p := range products {
@@ -42,25 +63,28 @@ func (c *Client) PostOrder(ctx context.Context, accountID string, products []Ord
 	}
 
 	res, err := c.service.PostOrder(ctx, &pb.PostOrderRequest{
-		AccountId: accountID,
-		Products:  protoProducts,
+		AccountId:      accountID,
+		Products:       protoProducts,
+		IdempotencyKey: idempotencyKey,
 	})
 	if err != nil {
-		return nil, err
+		return nil, errs.FromGRPC(err)
 	}
 
-	newOrder := res.Order
-	newOrderCreatedAt := time.Time{}
-	newOrderCreatedAt.UnmarshalBinary(newOrder.CreatedAt)
+	o := orderFromProto(res.Order)
+	return &o, nil
+}
 
-	return &Order{
-		ID:         newOrder.Id,
-		CreatedAt:  newOrderCreatedAt,
-		TotalPrice: newOrder.TotalPrice,
-		AccountID:  newOrder.AccountId,
-		Products:   products,
-	}, nil
+func (c *Client) GetOrder(ctx context.Context, id string) (*Order, error) {
+	res, err := c.service.GetOrder(ctx, &pb.GetOrderRequest{
+		Id: id,
+	})
+	if err != nil {
+		return nil, errs.FromGRPC(err)
+	}
 
+	o := orderFromProto(res.Order)
+	return &o, nil
 }
 
 func (c *Client) GetOrdersForAccount(ctx context.Context, accountID string) ([]Order, error) {
@@ -69,31 +93,61 @@ func (c *Client) GetOrdersForAccount(ctx context.Context, accountID string) ([]O
 		AccountId: accountID,
 	})
 	if err != nil {
-		return nil, err
+		return nil, errs.FromGRPC(err)
 	}
 
 	orders := []Order{}
 	for _, orderProto := range res.Orders {
-		newOrder := Order{
-			ID:         orderProto.Id,
-			TotalPrice: orderProto.TotalPrice,
-			AccountID:  orderProto.AccountId,
-		}
-		newOrder.CreatedAt = time.Time{}
-		newOrder.CreatedAt.UnmarshalBinary(orderProto.CreatedAt)
-		products := []OrderedProduct{}
-
-		for _, p := range orderProto.Products {
-			products = append(products, OrderedProduct{
-				ID:          p.Id,
-				Quantity:    p.Quantity,
-				Name:        p.Name,
-				Description: p.Description,
-				Price:       p.Price,
-			})
-		}
-		newOrder.Products = products
-		orders = append(orders, newOrder)
+		orders = append(orders, orderFromProto(orderProto))
 	}
 	return orders, nil
 }
+
+// UpdateOrderStatus moves an order to status on behalf of the caller
+// identified in ctx.
+func (c *Client) UpdateOrderStatus(ctx context.Context, id string, status Status) (*Order, error) {
+	res, err := c.service.UpdateOrderStatus(ctx, &pb.UpdateOrderStatusRequest{
+		Id:     id,
+		Status: pb.OrderStatus(status),
+	})
+	if err != nil {
+		return nil, errs.FromGRPC(err)
+	}
+
+	o := orderFromProto(res.Order)
+	return &o, nil
+}
+
+func orderFromProto(orderProto *pb.Order) Order {
+	newOrder := Order{
+		ID:         orderProto.Id,
+		TotalPrice: money.FromProto(orderProto.TotalPrice),
+		AccountID:  orderProto.AccountId,
+		Status:     Status(orderProto.Status),
+	}
+	newOrder.CreatedAt = time.Time{}
+	newOrder.CreatedAt.UnmarshalBinary(orderProto.CreatedAt)
+	products := []OrderedProduct{}
+
+	for _, p := range orderProto.Products {
+		products = append(products, OrderedProduct{
+			ID:          p.Id,
+			Quantity:    p.Quantity,
+			Name:        p.Name,
+			Description: p.Description,
+			Price:       money.FromProto(p.Price),
+		})
+	}
+	newOrder.Products = products
+
+	for _, c := range orderProto.History {
+		change := StatusChange{
+			Status:  Status(c.Status),
+			ActorID: c.ActorId,
+		}
+		change.ChangedAt.UnmarshalBinary(c.ChangedAt)
+		newOrder.History = append(newOrder.History, change)
+	}
+
+	return newOrder
+}
@@ -1,18 +1,44 @@
 package main
 
 import (
+	"context"
 	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/kelseyhightower/envconfig"
+	"github.com/rajan-marasini/ecom-microservice/grpcclient"
+	"github.com/rajan-marasini/ecom-microservice/logging"
+	"github.com/rajan-marasini/ecom-microservice/metrics"
+	"github.com/rajan-marasini/ecom-microservice/migrate"
+	"github.com/rajan-marasini/ecom-microservice/mtls"
 	"github.com/rajan-marasini/ecom-microservice/order"
+	"github.com/rajan-marasini/ecom-microservice/tracing"
 	"github.com/tinrab/retry"
 )
 
 type Config struct {
-	DatabaseURL string `envconfig:"DATABASE_URL"`
-	AccountURL  string `envconfig:"ACCOUNT_SERVICE_URL"`
-	CatalogURL  string `envconfig:"CATALOG_SERVICE_URL"`
+	DatabaseURL    string `envconfig:"DATABASE_URL"`
+	AccountURL     string `envconfig:"ACCOUNT_SERVICE_URL"`
+	CatalogURL     string `envconfig:"CATALOG_SERVICE_URL"`
+	StorageBackend string `envconfig:"STORAGE_BACKEND" default:"postgres"`
+	AutoMigrate    bool   `envconfig:"AUTO_MIGRATE" default:"true"`
+	// TLS enables mutual TLS with TLS_CERT_FILE, TLS_KEY_FILE and TLS_CA_FILE.
+	// The same certificate is used to serve and to call the other services.
+	TLS mtls.Config `envconfig:"TLS"`
+	// Client tunes the calls to the other services with CLIENT_TIMEOUT,
+	// CLIENT_MAX_ATTEMPTS, CLIENT_BREAKER_FAILURES and CLIENT_BREAKER_TIMEOUT.
+	Client grpcclient.Config `envconfig:"CLIENT"`
+	// ShutdownTimeout bounds how long in-flight requests may run after SIGTERM.
+	ShutdownTimeout time.Duration `envconfig:"SHUTDOWN_TIMEOUT" default:"15s"`
+	// MetricsPort serves Prometheus metrics at /metrics.
+	MetricsPort int `envconfig:"METRICS_PORT" default:"9090"`
+	// Log sets the lowest level logged with LOG_LEVEL (debug, info, warn or
+	// error) and the format with LOG_FORMAT (json or text).
+	Log logging.Config `envconfig:"LOG"`
 }
 
 func main() {
@@ -22,18 +48,77 @@ func main() {
 		log.Fatal(err)
 	}
 
-	var r order.Repository
-	retry.ForeverSleep(2*time.Second, func(_ int) (err error) {
-		r, err = order.NewPostgresRepository(cfg.DatabaseURL)
-		if err != nil {
-			log.Println(err)
+	if err := logging.Init(cfg.Log, "order"); err != nil {
+		log.Fatal(err)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate.Run(context.Background(), cfg.DatabaseURL, order.Migrations, os.Args[2:], os.Stdout); err != nil {
+			logging.Fatal("migration failed", "error", err)
 		}
-		return err
-	})
-	defer r.Close()
+		return
+	}
+
+	creds, err := mtls.Load(cfg.TLS)
+	if err != nil {
+		logging.Fatal("loading TLS credentials failed", "error", err)
+	}
+
+	shutdownTracing, err := tracing.Init(context.Background(), "order")
+	if err != nil {
+		logging.Fatal("initializing tracing failed", "error", err)
+	}
+
+	var r order.Repository
+	switch cfg.StorageBackend {
+	case "memory":
+		r = order.NewMemoryRepository()
+	case "postgres":
+		retry.ForeverSleep(2*time.Second, func(_ int) (err error) {
+			if cfg.AutoMigrate {
+				if err = migrate.Run(context.Background(), cfg.DatabaseURL, order.Migrations, []string{"up"}, log.Writer()); err != nil {
+					slog.Error("migrating database failed, retrying", "error", err)
+					return err
+				}
+			}
+			r, err = order.NewPostgresRepository(cfg.DatabaseURL)
+			if err != nil {
+				slog.Error("connecting to storage failed, retrying", "error", err)
+			}
+			return err
+		})
+	default:
+		logging.Fatal("unknown storage backend", "backend", cfg.StorageBackend)
+	}
 
-	log.Println("Listening on port 8080")
 	s := order.NewService(r)
-	log.Fatal(order.ListenGRPC(s, cfg.AccountURL, cfg.CatalogURL, 8080))
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		slog.Error("metrics server stopped", "error", metrics.ListenAndServe(cfg.MetricsPort))
+	}()
+
+	srv, err := order.ListenGRPC(ctx, s, cfg.AccountURL, cfg.CatalogURL, 8080, creds, grpcclient.Options{Config: cfg.Client, Credentials: creds}, r.Ping)
+	if err != nil {
+		r.Close()
+		logging.Fatal("starting gRPC server failed", "error", err)
+	}
+	slog.Info("listening", "port", 8080)
+
+	select {
+	case <-ctx.Done():
+		slog.Info("shutting down")
+	case <-srv.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	err = srv.Shutdown(shutdownCtx)
+	r.Close()
+	shutdownTracing(shutdownCtx)
+	if err != nil {
+		logging.Fatal("shutdown failed", "error", err)
+	}
 }
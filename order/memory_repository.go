@@ -0,0 +1,140 @@
+package order
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/rajan-marasini/ecom-microservice/errs"
+)
+
+type memoryRepository struct {
+	mu     sync.RWMutex
+	orders map[string]Order
+}
+
+// NewMemoryRepository returns a Repository that keeps orders in process
+// memory. It mirrors the Postgres implementation, including its ordering, and
+// is meant for demos and tests.
+func NewMemoryRepository() Repository {
+	return &memoryRepository{
+		orders: map[string]Order{},
+	}
+}
+
+func (r *memoryRepository) Close() {
+}
+
+func (r *memoryRepository) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (r *memoryRepository) PutOrder(ctx context.Context, o Order) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.orders[o.ID]; ok {
+		return errs.New(errs.AlreadyExists, "order %s already exists", o.ID)
+	}
+	if o.IdempotencyKey != "" {
+		if _, ok := r.findByIdempotencyKey(o.AccountID, o.IdempotencyKey); ok {
+			return errs.New(errs.AlreadyExists, "an order was already placed with idempotency key %s", o.IdempotencyKey)
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, p := range o.Products {
+		if seen[p.ID] {
+			return errs.New(errs.InvalidArgument, "order %s contains product %s more than once", o.ID, p.ID)
+		}
+		seen[p.ID] = true
+	}
+
+	r.orders[o.ID] = copyOrder(o)
+
+	return nil
+}
+
+func (r *memoryRepository) GetOrder(ctx context.Context, id string) (*Order, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	o, ok := r.orders[id]
+	if !ok {
+		return nil, errs.New(errs.NotFound, "order %s not found", id)
+	}
+	o = copyOrder(o)
+
+	return &o, nil
+}
+
+func (r *memoryRepository) GetOrderByIdempotencyKey(ctx context.Context, accountID, key string) (*Order, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	o, ok := r.findByIdempotencyKey(accountID, key)
+	if !ok {
+		return nil, errs.New(errs.NotFound, "no order placed with idempotency key %s", key)
+	}
+	o = copyOrder(o)
+
+	return &o, nil
+}
+
+// findByIdempotencyKey scans for the order, as the unique index on
+// orders.account_id and idempotency_key would find it.
+func (r *memoryRepository) findByIdempotencyKey(accountID, key string) (Order, bool) {
+	for _, o := range r.orders {
+		if o.AccountID == accountID && o.IdempotencyKey == key {
+			return o, true
+		}
+	}
+	return Order{}, false
+}
+
+func (r *memoryRepository) UpdateOrderStatus(ctx context.Context, id string, from Status, change StatusChange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	o, ok := r.orders[id]
+	if !ok {
+		return errs.New(errs.NotFound, "order %s not found", id)
+	}
+	if o.Status != from {
+		return errs.New(errs.FailedPrecondition, "order %s is no longer %s", id, from)
+	}
+
+	o = copyOrder(o)
+	o.Status = change.Status
+	o.History = append(o.History, change)
+	r.orders[id] = o
+
+	return nil
+}
+
+func (r *memoryRepository) GetOrdersForAccount(ctx context.Context, accountID string) ([]Order, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var orders []Order
+	for _, o := range r.orders {
+		if o.AccountID == accountID {
+			orders = append(orders, copyOrder(o))
+		}
+	}
+
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].ID < orders[j].ID
+	})
+
+	return orders, nil
+}
+
+// copyOrder detaches the product list and status history so callers can't
+// mutate stored orders.
+func copyOrder(o Order) Order {
+	o.Products = slices.Clone(o.Products)
+	o.History = slices.Clone(o.History)
+	return o
+}
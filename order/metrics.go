@@ -0,0 +1,27 @@
+package order
+
+import (
+	"math"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rajan-marasini/ecom-microservice/money"
+)
+
+var (
+	ordersPlaced = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "orders_placed_total",
+		Help: "Number of orders placed, by currency.",
+	}, []string{"currency"})
+
+	orderValue = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "order_value_total",
+		Help: "Sum of the totals of placed orders in major currency units, by currency.",
+	}, []string{"currency"})
+)
+
+func recordOrderPlaced(o Order) {
+	total := o.TotalPrice
+	ordersPlaced.WithLabelValues(total.Currency).Inc()
+	orderValue.WithLabelValues(total.Currency).Add(float64(total.Amount) / math.Pow10(money.Exponent(total.Currency)))
+}
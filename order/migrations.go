@@ -0,0 +1,13 @@
+package order
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migrations holds the versioned schema of the Postgres repository, for use
+// with the migrate package.
+var Migrations, _ = fs.Sub(migrationFiles, "migrations")
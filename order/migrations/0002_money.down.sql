@@ -0,0 +1,2 @@
+ALTER TABLE orders ALTER COLUMN total_price TYPE MONEY USING (total_price / 100.0)::numeric::MONEY;
+ALTER TABLE orders DROP COLUMN currency;
@@ -0,0 +1,5 @@
+-- Store order totals as integer minor units with an explicit currency
+-- instead of the locale dependent MONEY type.
+ALTER TABLE orders ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD';
+ALTER TABLE orders ALTER COLUMN currency DROP DEFAULT;
+ALTER TABLE orders ALTER COLUMN total_price TYPE BIGINT USING round(total_price::numeric * 100)::BIGINT;
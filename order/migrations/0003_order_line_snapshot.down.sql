@@ -0,0 +1,3 @@
+ALTER TABLE order_products DROP COLUMN unit_price;
+ALTER TABLE order_products DROP COLUMN description;
+ALTER TABLE order_products DROP COLUMN name;
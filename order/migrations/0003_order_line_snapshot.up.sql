@@ -0,0 +1,5 @@
+-- Snapshot what the customer saw at purchase time. Lines written before this
+-- migration keep NULLs and are filled in from the catalog when read.
+ALTER TABLE order_products ADD COLUMN name TEXT;
+ALTER TABLE order_products ADD COLUMN description TEXT;
+ALTER TABLE order_products ADD COLUMN unit_price BIGINT;
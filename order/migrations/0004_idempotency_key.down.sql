@@ -0,0 +1,2 @@
+DROP INDEX orders_account_id_idempotency_key_key;
+ALTER TABLE orders DROP COLUMN idempotency_key;
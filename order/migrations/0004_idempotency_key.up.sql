@@ -0,0 +1,5 @@
+-- A client may retry placing an order with the key it sent first; the index
+-- keeps one order per account and key. Orders placed without a key are NULL
+-- and never conflict.
+ALTER TABLE orders ADD COLUMN idempotency_key TEXT;
+CREATE UNIQUE INDEX orders_account_id_idempotency_key_key ON orders (account_id, idempotency_key);
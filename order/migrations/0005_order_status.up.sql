@@ -0,0 +1,16 @@
+-- Orders placed before statuses existed start out pending, with a history
+-- entry for when they were placed.
+ALTER TABLE orders ADD COLUMN status TEXT NOT NULL DEFAULT 'pending'
+    CHECK (status IN ('pending', 'confirmed', 'paid', 'shipped', 'delivered', 'cancelled', 'refunded'));
+
+CREATE TABLE IF NOT EXISTS order_status_history (
+    id BIGSERIAL PRIMARY KEY,
+    order_id CHAR(27) NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    status TEXT NOT NULL,
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    actor_id TEXT NOT NULL
+);
+CREATE INDEX order_status_history_order_id_idx ON order_status_history (order_id);
+
+INSERT INTO order_status_history (order_id, status, changed_at, actor_id)
+SELECT id, 'pending', created_at, account_id FROM orders;
@@ -7,6 +7,7 @@
 package pb
 
 import (
+	pb "github.com/rajan-marasini/ecom-microservice/money/pb"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	reflect "reflect"
@@ -21,13 +22,79 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// OrderStatus is the lifecycle state of an order. Cancelled and refunded are
+// final.
+type OrderStatus int32
+
+const (
+	OrderStatus_ORDER_STATUS_PENDING   OrderStatus = 0
+	OrderStatus_ORDER_STATUS_CONFIRMED OrderStatus = 1
+	OrderStatus_ORDER_STATUS_PAID      OrderStatus = 2
+	OrderStatus_ORDER_STATUS_SHIPPED   OrderStatus = 3
+	OrderStatus_ORDER_STATUS_DELIVERED OrderStatus = 4
+	OrderStatus_ORDER_STATUS_CANCELLED OrderStatus = 5
+	OrderStatus_ORDER_STATUS_REFUNDED  OrderStatus = 6
+)
+
+// Enum value maps for OrderStatus.
+var (
+	OrderStatus_name = map[int32]string{
+		0: "ORDER_STATUS_PENDING",
+		1: "ORDER_STATUS_CONFIRMED",
+		2: "ORDER_STATUS_PAID",
+		3: "ORDER_STATUS_SHIPPED",
+		4: "ORDER_STATUS_DELIVERED",
+		5: "ORDER_STATUS_CANCELLED",
+		6: "ORDER_STATUS_REFUNDED",
+	}
+	OrderStatus_value = map[string]int32{
+		"ORDER_STATUS_PENDING":   0,
+		"ORDER_STATUS_CONFIRMED": 1,
+		"ORDER_STATUS_PAID":      2,
+		"ORDER_STATUS_SHIPPED":   3,
+		"ORDER_STATUS_DELIVERED": 4,
+		"ORDER_STATUS_CANCELLED": 5,
+		"ORDER_STATUS_REFUNDED":  6,
+	}
+)
+
+func (x OrderStatus) Enum() *OrderStatus {
+	p := new(OrderStatus)
+	*p = x
+	return p
+}
+
+func (x OrderStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (OrderStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_order_proto_enumTypes[0].Descriptor()
+}
+
+func (OrderStatus) Type() protoreflect.EnumType {
+	return &file_order_proto_enumTypes[0]
+}
+
+func (x OrderStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use OrderStatus.Descriptor instead.
+func (OrderStatus) EnumDescriptor() ([]byte, []int) {
+	return file_order_proto_rawDescGZIP(), []int{0}
+}
+
 type Order struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	CreatedAt     []byte                 `protobuf:"bytes,2,opt,name=createdAt,proto3" json:"createdAt,omitempty"`
-	AccountId     string                 `protobuf:"bytes,3,opt,name=accountId,proto3" json:"accountId,omitempty"`
-	TotalPrice    float64                `protobuf:"fixed64,4,opt,name=totalPrice,proto3" json:"totalPrice,omitempty"`
-	Products      []*Order_OrderProduct  `protobuf:"bytes,5,rep,name=products,proto3" json:"products,omitempty"`
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Id         string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	CreatedAt  []byte                 `protobuf:"bytes,2,opt,name=createdAt,proto3" json:"createdAt,omitempty"`
+	AccountId  string                 `protobuf:"bytes,3,opt,name=accountId,proto3" json:"accountId,omitempty"`
+	TotalPrice *pb.Money              `protobuf:"bytes,6,opt,name=totalPrice,proto3" json:"totalPrice,omitempty"`
+	Products   []*Order_OrderProduct  `protobuf:"bytes,5,rep,name=products,proto3" json:"products,omitempty"`
+	Status     OrderStatus            `protobuf:"varint,7,opt,name=status,proto3,enum=pb.OrderStatus" json:"status,omitempty"`
+	// history lists every status the order has had, oldest first.
+	History       []*StatusChange `protobuf:"bytes,8,rep,name=history,proto3" json:"history,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -83,11 +150,11 @@ func (x *Order) GetAccountId() string {
 	return ""
 }
 
-func (x *Order) GetTotalPrice() float64 {
+func (x *Order) GetTotalPrice() *pb.Money {
 	if x != nil {
 		return x.TotalPrice
 	}
-	return 0
+	return nil
 }
 
 func (x *Order) GetProducts() []*Order_OrderProduct {
@@ -97,17 +164,95 @@ func (x *Order) GetProducts() []*Order_OrderProduct {
 	return nil
 }
 
-type PostOrderRequest struct {
-	state         protoimpl.MessageState           `protogen:"open.v1"`
-	AccountId     string                           `protobuf:"bytes,2,opt,name=accountId,proto3" json:"accountId,omitempty"`
-	Products      []*PostOrderRequest_OrderProduct `protobuf:"bytes,3,rep,name=products,proto3" json:"products,omitempty"`
+func (x *Order) GetStatus() OrderStatus {
+	if x != nil {
+		return x.Status
+	}
+	return OrderStatus_ORDER_STATUS_PENDING
+}
+
+func (x *Order) GetHistory() []*StatusChange {
+	if x != nil {
+		return x.History
+	}
+	return nil
+}
+
+type StatusChange struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Status    OrderStatus            `protobuf:"varint,1,opt,name=status,proto3,enum=pb.OrderStatus" json:"status,omitempty"`
+	ChangedAt []byte                 `protobuf:"bytes,2,opt,name=changedAt,proto3" json:"changedAt,omitempty"`
+	// actorId is the account that made the change.
+	ActorId       string `protobuf:"bytes,3,opt,name=actorId,proto3" json:"actorId,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
+func (x *StatusChange) Reset() {
+	*x = StatusChange{}
+	mi := &file_order_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatusChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusChange) ProtoMessage() {}
+
+func (x *StatusChange) ProtoReflect() protoreflect.Message {
+	mi := &file_order_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusChange.ProtoReflect.Descriptor instead.
+func (*StatusChange) Descriptor() ([]byte, []int) {
+	return file_order_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *StatusChange) GetStatus() OrderStatus {
+	if x != nil {
+		return x.Status
+	}
+	return OrderStatus_ORDER_STATUS_PENDING
+}
+
+func (x *StatusChange) GetChangedAt() []byte {
+	if x != nil {
+		return x.ChangedAt
+	}
+	return nil
+}
+
+func (x *StatusChange) GetActorId() string {
+	if x != nil {
+		return x.ActorId
+	}
+	return ""
+}
+
+type PostOrderRequest struct {
+	state     protoimpl.MessageState           `protogen:"open.v1"`
+	AccountId string                           `protobuf:"bytes,2,opt,name=accountId,proto3" json:"accountId,omitempty"`
+	Products  []*PostOrderRequest_OrderProduct `protobuf:"bytes,3,rep,name=products,proto3" json:"products,omitempty"`
+	// idempotencyKey, when set, makes retries of the request return the
+	// order it placed first instead of placing another.
+	IdempotencyKey string `protobuf:"bytes,4,opt,name=idempotencyKey,proto3" json:"idempotencyKey,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
 func (x *PostOrderRequest) Reset() {
 	*x = PostOrderRequest{}
-	mi := &file_order_proto_msgTypes[1]
+	mi := &file_order_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -119,7 +264,7 @@ func (x *PostOrderRequest) String() string {
 func (*PostOrderRequest) ProtoMessage() {}
 
 func (x *PostOrderRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_order_proto_msgTypes[1]
+	mi := &file_order_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -132,7 +277,7 @@ func (x *PostOrderRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PostOrderRequest.ProtoReflect.Descriptor instead.
 func (*PostOrderRequest) Descriptor() ([]byte, []int) {
-	return file_order_proto_rawDescGZIP(), []int{1}
+	return file_order_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *PostOrderRequest) GetAccountId() string {
@@ -149,6 +294,13 @@ func (x *PostOrderRequest) GetProducts() []*PostOrderRequest_OrderProduct {
 	return nil
 }
 
+func (x *PostOrderRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
 type PostOrderResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Order         *Order                 `protobuf:"bytes,1,opt,name=order,proto3" json:"order,omitempty"`
@@ -158,7 +310,7 @@ type PostOrderResponse struct {
 
 func (x *PostOrderResponse) Reset() {
 	*x = PostOrderResponse{}
-	mi := &file_order_proto_msgTypes[2]
+	mi := &file_order_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -170,7 +322,7 @@ func (x *PostOrderResponse) String() string {
 func (*PostOrderResponse) ProtoMessage() {}
 
 func (x *PostOrderResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_order_proto_msgTypes[2]
+	mi := &file_order_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -183,7 +335,7 @@ func (x *PostOrderResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PostOrderResponse.ProtoReflect.Descriptor instead.
 func (*PostOrderResponse) Descriptor() ([]byte, []int) {
-	return file_order_proto_rawDescGZIP(), []int{2}
+	return file_order_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *PostOrderResponse) GetOrder() *Order {
@@ -202,7 +354,7 @@ type GetOrderRequest struct {
 
 func (x *GetOrderRequest) Reset() {
 	*x = GetOrderRequest{}
-	mi := &file_order_proto_msgTypes[3]
+	mi := &file_order_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -214,7 +366,7 @@ func (x *GetOrderRequest) String() string {
 func (*GetOrderRequest) ProtoMessage() {}
 
 func (x *GetOrderRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_order_proto_msgTypes[3]
+	mi := &file_order_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -227,7 +379,7 @@ func (x *GetOrderRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetOrderRequest.ProtoReflect.Descriptor instead.
 func (*GetOrderRequest) Descriptor() ([]byte, []int) {
-	return file_order_proto_rawDescGZIP(), []int{3}
+	return file_order_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *GetOrderRequest) GetId() string {
@@ -246,7 +398,7 @@ type GetOrderResponse struct {
 
 func (x *GetOrderResponse) Reset() {
 	*x = GetOrderResponse{}
-	mi := &file_order_proto_msgTypes[4]
+	mi := &file_order_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -258,7 +410,7 @@ func (x *GetOrderResponse) String() string {
 func (*GetOrderResponse) ProtoMessage() {}
 
 func (x *GetOrderResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_order_proto_msgTypes[4]
+	mi := &file_order_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -271,7 +423,7 @@ func (x *GetOrderResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetOrderResponse.ProtoReflect.Descriptor instead.
 func (*GetOrderResponse) Descriptor() ([]byte, []int) {
-	return file_order_proto_rawDescGZIP(), []int{4}
+	return file_order_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *GetOrderResponse) GetOrder() *Order {
@@ -290,7 +442,7 @@ type GetOrdersForAccountRequest struct {
 
 func (x *GetOrdersForAccountRequest) Reset() {
 	*x = GetOrdersForAccountRequest{}
-	mi := &file_order_proto_msgTypes[5]
+	mi := &file_order_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -302,7 +454,7 @@ func (x *GetOrdersForAccountRequest) String() string {
 func (*GetOrdersForAccountRequest) ProtoMessage() {}
 
 func (x *GetOrdersForAccountRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_order_proto_msgTypes[5]
+	mi := &file_order_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -315,7 +467,7 @@ func (x *GetOrdersForAccountRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetOrdersForAccountRequest.ProtoReflect.Descriptor instead.
 func (*GetOrdersForAccountRequest) Descriptor() ([]byte, []int) {
-	return file_order_proto_rawDescGZIP(), []int{5}
+	return file_order_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *GetOrdersForAccountRequest) GetAccountId() string {
@@ -334,7 +486,7 @@ type GetOrdersForAccountResponse struct {
 
 func (x *GetOrdersForAccountResponse) Reset() {
 	*x = GetOrdersForAccountResponse{}
-	mi := &file_order_proto_msgTypes[6]
+	mi := &file_order_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -346,7 +498,7 @@ func (x *GetOrdersForAccountResponse) String() string {
 func (*GetOrdersForAccountResponse) ProtoMessage() {}
 
 func (x *GetOrdersForAccountResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_order_proto_msgTypes[6]
+	mi := &file_order_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -359,7 +511,7 @@ func (x *GetOrdersForAccountResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetOrdersForAccountResponse.ProtoReflect.Descriptor instead.
 func (*GetOrdersForAccountResponse) Descriptor() ([]byte, []int) {
-	return file_order_proto_rawDescGZIP(), []int{6}
+	return file_order_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *GetOrdersForAccountResponse) GetOrders() []*Order {
@@ -369,12 +521,111 @@ func (x *GetOrdersForAccountResponse) GetOrders() []*Order {
 	return nil
 }
 
+// UpdateOrderStatusRequest moves an order to status, which its current
+// status must allow. Merchants may make any such change; the account that
+// placed an order may only cancel it.
+type UpdateOrderStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Status        OrderStatus            `protobuf:"varint,2,opt,name=status,proto3,enum=pb.OrderStatus" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateOrderStatusRequest) Reset() {
+	*x = UpdateOrderStatusRequest{}
+	mi := &file_order_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateOrderStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateOrderStatusRequest) ProtoMessage() {}
+
+func (x *UpdateOrderStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_order_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateOrderStatusRequest.ProtoReflect.Descriptor instead.
+func (*UpdateOrderStatusRequest) Descriptor() ([]byte, []int) {
+	return file_order_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *UpdateOrderStatusRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *UpdateOrderStatusRequest) GetStatus() OrderStatus {
+	if x != nil {
+		return x.Status
+	}
+	return OrderStatus_ORDER_STATUS_PENDING
+}
+
+type UpdateOrderStatusResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Order         *Order                 `protobuf:"bytes,1,opt,name=order,proto3" json:"order,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateOrderStatusResponse) Reset() {
+	*x = UpdateOrderStatusResponse{}
+	mi := &file_order_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateOrderStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateOrderStatusResponse) ProtoMessage() {}
+
+func (x *UpdateOrderStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_order_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateOrderStatusResponse.ProtoReflect.Descriptor instead.
+func (*UpdateOrderStatusResponse) Descriptor() ([]byte, []int) {
+	return file_order_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *UpdateOrderStatusResponse) GetOrder() *Order {
+	if x != nil {
+		return x.Order
+	}
+	return nil
+}
+
 type Order_OrderProduct struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
 	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
 	Description   string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
-	Price         float64                `protobuf:"fixed64,4,opt,name=price,proto3" json:"price,omitempty"`
+	Price         *pb.Money              `protobuf:"bytes,6,opt,name=price,proto3" json:"price,omitempty"`
 	Quantity      uint32                 `protobuf:"varint,5,opt,name=quantity,proto3" json:"quantity,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -382,7 +633,7 @@ type Order_OrderProduct struct {
 
 func (x *Order_OrderProduct) Reset() {
 	*x = Order_OrderProduct{}
-	mi := &file_order_proto_msgTypes[7]
+	mi := &file_order_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -394,7 +645,7 @@ func (x *Order_OrderProduct) String() string {
 func (*Order_OrderProduct) ProtoMessage() {}
 
 func (x *Order_OrderProduct) ProtoReflect() protoreflect.Message {
-	mi := &file_order_proto_msgTypes[7]
+	mi := &file_order_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -431,11 +682,11 @@ func (x *Order_OrderProduct) GetDescription() string {
 	return ""
 }
 
-func (x *Order_OrderProduct) GetPrice() float64 {
+func (x *Order_OrderProduct) GetPrice() *pb.Money {
 	if x != nil {
 		return x.Price
 	}
-	return 0
+	return nil
 }
 
 func (x *Order_OrderProduct) GetQuantity() uint32 {
@@ -455,7 +706,7 @@ type PostOrderRequest_OrderProduct struct {
 
 func (x *PostOrderRequest_OrderProduct) Reset() {
 	*x = PostOrderRequest_OrderProduct{}
-	mi := &file_order_proto_msgTypes[8]
+	mi := &file_order_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -467,7 +718,7 @@ func (x *PostOrderRequest_OrderProduct) String() string {
 func (*PostOrderRequest_OrderProduct) ProtoMessage() {}
 
 func (x *PostOrderRequest_OrderProduct) ProtoReflect() protoreflect.Message {
-	mi := &file_order_proto_msgTypes[8]
+	mi := &file_order_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -480,7 +731,7 @@ func (x *PostOrderRequest_OrderProduct) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PostOrderRequest_OrderProduct.ProtoReflect.Descriptor instead.
 func (*PostOrderRequest_OrderProduct) Descriptor() ([]byte, []int) {
-	return file_order_proto_rawDescGZIP(), []int{1, 0}
+	return file_order_proto_rawDescGZIP(), []int{2, 0}
 }
 
 func (x *PostOrderRequest_OrderProduct) GetProductId() string {
@@ -501,24 +752,31 @@ var File_order_proto protoreflect.FileDescriptor
 
 const file_order_proto_rawDesc = "" +
 	"\n" +
-	"\vorder.proto\x12\x02pb\"\xb0\x02\n" +
+	"\vorder.proto\x12\x02pb\x1a\x11money/money.proto\"\xad\x03\n" +
 	"\x05Order\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1c\n" +
 	"\tcreatedAt\x18\x02 \x01(\fR\tcreatedAt\x12\x1c\n" +
-	"\taccountId\x18\x03 \x01(\tR\taccountId\x12\x1e\n" +
+	"\taccountId\x18\x03 \x01(\tR\taccountId\x12,\n" +
 	"\n" +
-	"totalPrice\x18\x04 \x01(\x01R\n" +
+	"totalPrice\x18\x06 \x01(\v2\f.money.MoneyR\n" +
 	"totalPrice\x122\n" +
-	"\bproducts\x18\x05 \x03(\v2\x16.pb.Order.OrderProductR\bproducts\x1a\x86\x01\n" +
+	"\bproducts\x18\x05 \x03(\v2\x16.pb.Order.OrderProductR\bproducts\x12'\n" +
+	"\x06status\x18\a \x01(\x0e2\x0f.pb.OrderStatusR\x06status\x12*\n" +
+	"\ahistory\x18\b \x03(\v2\x10.pb.StatusChangeR\ahistory\x1a\x9a\x01\n" +
 	"\fOrderProduct\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
 	"\x04name\x18\x02 \x01(\tR\x04name\x12 \n" +
-	"\vdescription\x18\x03 \x01(\tR\vdescription\x12\x14\n" +
-	"\x05price\x18\x04 \x01(\x01R\x05price\x12\x1a\n" +
-	"\bquantity\x18\x05 \x01(\rR\bquantity\"\xb9\x01\n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\x12\"\n" +
+	"\x05price\x18\x06 \x01(\v2\f.money.MoneyR\x05price\x12\x1a\n" +
+	"\bquantity\x18\x05 \x01(\rR\bquantityJ\x04\b\x04\x10\x05J\x04\b\x04\x10\x05\"o\n" +
+	"\fStatusChange\x12'\n" +
+	"\x06status\x18\x01 \x01(\x0e2\x0f.pb.OrderStatusR\x06status\x12\x1c\n" +
+	"\tchangedAt\x18\x02 \x01(\fR\tchangedAt\x12\x18\n" +
+	"\aactorId\x18\x03 \x01(\tR\aactorId\"\xe1\x01\n" +
 	"\x10PostOrderRequest\x12\x1c\n" +
 	"\taccountId\x18\x02 \x01(\tR\taccountId\x12=\n" +
-	"\bproducts\x18\x03 \x03(\v2!.pb.PostOrderRequest.OrderProductR\bproducts\x1aH\n" +
+	"\bproducts\x18\x03 \x03(\v2!.pb.PostOrderRequest.OrderProductR\bproducts\x12&\n" +
+	"\x0eidempotencyKey\x18\x04 \x01(\tR\x0eidempotencyKey\x1aH\n" +
 	"\fOrderProduct\x12\x1c\n" +
 	"\tproductId\x18\x02 \x01(\tR\tproductId\x12\x1a\n" +
 	"\bquantity\x18\x03 \x01(\rR\bquantity\"4\n" +
@@ -531,10 +789,25 @@ const file_order_proto_rawDesc = "" +
 	"\x1aGetOrdersForAccountRequest\x12\x1c\n" +
 	"\taccountId\x18\x01 \x01(\tR\taccountId\"@\n" +
 	"\x1bGetOrdersForAccountResponse\x12!\n" +
-	"\x06orders\x18\x01 \x03(\v2\t.pb.OrderR\x06orders2\xa4\x01\n" +
+	"\x06orders\x18\x01 \x03(\v2\t.pb.OrderR\x06orders\"S\n" +
+	"\x18UpdateOrderStatusRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12'\n" +
+	"\x06status\x18\x02 \x01(\x0e2\x0f.pb.OrderStatusR\x06status\"<\n" +
+	"\x19UpdateOrderStatusResponse\x12\x1f\n" +
+	"\x05order\x18\x01 \x01(\v2\t.pb.OrderR\x05order*\xc7\x01\n" +
+	"\vOrderStatus\x12\x18\n" +
+	"\x14ORDER_STATUS_PENDING\x10\x00\x12\x1a\n" +
+	"\x16ORDER_STATUS_CONFIRMED\x10\x01\x12\x15\n" +
+	"\x11ORDER_STATUS_PAID\x10\x02\x12\x18\n" +
+	"\x14ORDER_STATUS_SHIPPED\x10\x03\x12\x1a\n" +
+	"\x16ORDER_STATUS_DELIVERED\x10\x04\x12\x1a\n" +
+	"\x16ORDER_STATUS_CANCELLED\x10\x05\x12\x19\n" +
+	"\x15ORDER_STATUS_REFUNDED\x10\x062\xb1\x02\n" +
 	"\fOrderService\x12:\n" +
-	"\tPostOrder\x12\x14.pb.PostOrderRequest\x1a\x15.pb.PostOrderResponse\"\x00\x12X\n" +
-	"\x13GetOrdersForAccount\x12\x1e.pb.GetOrdersForAccountRequest\x1a\x1f.pb.GetOrdersForAccountResponse\"\x00B6Z4github.com/rajan-marasini/ecom-microservice/order/pbb\x06proto3"
+	"\tPostOrder\x12\x14.pb.PostOrderRequest\x1a\x15.pb.PostOrderResponse\"\x00\x127\n" +
+	"\bGetOrder\x12\x13.pb.GetOrderRequest\x1a\x14.pb.GetOrderResponse\"\x00\x12X\n" +
+	"\x13GetOrdersForAccount\x12\x1e.pb.GetOrdersForAccountRequest\x1a\x1f.pb.GetOrdersForAccountResponse\"\x00\x12R\n" +
+	"\x11UpdateOrderStatus\x12\x1c.pb.UpdateOrderStatusRequest\x1a\x1d.pb.UpdateOrderStatusResponse\"\x00B6Z4github.com/rajan-marasini/ecom-microservice/order/pbb\x06proto3"
 
 var (
 	file_order_proto_rawDescOnce sync.Once
@@ -548,33 +821,50 @@ func file_order_proto_rawDescGZIP() []byte {
 	return file_order_proto_rawDescData
 }
 
-var file_order_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_order_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_order_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
 var file_order_proto_goTypes = []any{
-	(*Order)(nil),                         // 0: pb.Order
-	(*PostOrderRequest)(nil),              // 1: pb.PostOrderRequest
-	(*PostOrderResponse)(nil),             // 2: pb.PostOrderResponse
-	(*GetOrderRequest)(nil),               // 3: pb.GetOrderRequest
-	(*GetOrderResponse)(nil),              // 4: pb.GetOrderResponse
-	(*GetOrdersForAccountRequest)(nil),    // 5: pb.GetOrdersForAccountRequest
-	(*GetOrdersForAccountResponse)(nil),   // 6: pb.GetOrdersForAccountResponse
-	(*Order_OrderProduct)(nil),            // 7: pb.Order.OrderProduct
-	(*PostOrderRequest_OrderProduct)(nil), // 8: pb.PostOrderRequest.OrderProduct
+	(OrderStatus)(0),                      // 0: pb.OrderStatus
+	(*Order)(nil),                         // 1: pb.Order
+	(*StatusChange)(nil),                  // 2: pb.StatusChange
+	(*PostOrderRequest)(nil),              // 3: pb.PostOrderRequest
+	(*PostOrderResponse)(nil),             // 4: pb.PostOrderResponse
+	(*GetOrderRequest)(nil),               // 5: pb.GetOrderRequest
+	(*GetOrderResponse)(nil),              // 6: pb.GetOrderResponse
+	(*GetOrdersForAccountRequest)(nil),    // 7: pb.GetOrdersForAccountRequest
+	(*GetOrdersForAccountResponse)(nil),   // 8: pb.GetOrdersForAccountResponse
+	(*UpdateOrderStatusRequest)(nil),      // 9: pb.UpdateOrderStatusRequest
+	(*UpdateOrderStatusResponse)(nil),     // 10: pb.UpdateOrderStatusResponse
+	(*Order_OrderProduct)(nil),            // 11: pb.Order.OrderProduct
+	(*PostOrderRequest_OrderProduct)(nil), // 12: pb.PostOrderRequest.OrderProduct
+	(*pb.Money)(nil),                      // 13: money.Money
 }
 var file_order_proto_depIdxs = []int32{
-	7, // 0: pb.Order.products:type_name -> pb.Order.OrderProduct
-	8, // 1: pb.PostOrderRequest.products:type_name -> pb.PostOrderRequest.OrderProduct
-	0, // 2: pb.PostOrderResponse.order:type_name -> pb.Order
-	0, // 3: pb.GetOrderResponse.order:type_name -> pb.Order
-	0, // 4: pb.GetOrdersForAccountResponse.orders:type_name -> pb.Order
-	1, // 5: pb.OrderService.PostOrder:input_type -> pb.PostOrderRequest
-	5, // 6: pb.OrderService.GetOrdersForAccount:input_type -> pb.GetOrdersForAccountRequest
-	2, // 7: pb.OrderService.PostOrder:output_type -> pb.PostOrderResponse
-	6, // 8: pb.OrderService.GetOrdersForAccount:output_type -> pb.GetOrdersForAccountResponse
-	7, // [7:9] is the sub-list for method output_type
-	5, // [5:7] is the sub-list for method input_type
-	5, // [5:5] is the sub-list for extension type_name
-	5, // [5:5] is the sub-list for extension extendee
-	0, // [0:5] is the sub-list for field type_name
+	13, // 0: pb.Order.totalPrice:type_name -> money.Money
+	11, // 1: pb.Order.products:type_name -> pb.Order.OrderProduct
+	0,  // 2: pb.Order.status:type_name -> pb.OrderStatus
+	2,  // 3: pb.Order.history:type_name -> pb.StatusChange
+	0,  // 4: pb.StatusChange.status:type_name -> pb.OrderStatus
+	12, // 5: pb.PostOrderRequest.products:type_name -> pb.PostOrderRequest.OrderProduct
+	1,  // 6: pb.PostOrderResponse.order:type_name -> pb.Order
+	1,  // 7: pb.GetOrderResponse.order:type_name -> pb.Order
+	1,  // 8: pb.GetOrdersForAccountResponse.orders:type_name -> pb.Order
+	0,  // 9: pb.UpdateOrderStatusRequest.status:type_name -> pb.OrderStatus
+	1,  // 10: pb.UpdateOrderStatusResponse.order:type_name -> pb.Order
+	13, // 11: pb.Order.OrderProduct.price:type_name -> money.Money
+	3,  // 12: pb.OrderService.PostOrder:input_type -> pb.PostOrderRequest
+	5,  // 13: pb.OrderService.GetOrder:input_type -> pb.GetOrderRequest
+	7,  // 14: pb.OrderService.GetOrdersForAccount:input_type -> pb.GetOrdersForAccountRequest
+	9,  // 15: pb.OrderService.UpdateOrderStatus:input_type -> pb.UpdateOrderStatusRequest
+	4,  // 16: pb.OrderService.PostOrder:output_type -> pb.PostOrderResponse
+	6,  // 17: pb.OrderService.GetOrder:output_type -> pb.GetOrderResponse
+	8,  // 18: pb.OrderService.GetOrdersForAccount:output_type -> pb.GetOrdersForAccountResponse
+	10, // 19: pb.OrderService.UpdateOrderStatus:output_type -> pb.UpdateOrderStatusResponse
+	16, // [16:20] is the sub-list for method output_type
+	12, // [12:16] is the sub-list for method input_type
+	12, // [12:12] is the sub-list for extension type_name
+	12, // [12:12] is the sub-list for extension extendee
+	0,  // [0:12] is the sub-list for field type_name
 }
 
 func init() { file_order_proto_init() }
@@ -587,13 +877,14 @@ func file_order_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_order_proto_rawDesc), len(file_order_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   9,
+			NumEnums:      1,
+			NumMessages:   12,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_order_proto_goTypes,
 		DependencyIndexes: file_order_proto_depIdxs,
+		EnumInfos:         file_order_proto_enumTypes,
 		MessageInfos:      file_order_proto_msgTypes,
 	}.Build()
 	File_order_proto = out.File
@@ -3,15 +3,28 @@ package order
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"time"
 
 	"github.com/lib/pq"
+	"github.com/rajan-marasini/ecom-microservice/errs"
+	"github.com/rajan-marasini/ecom-microservice/metrics"
+	"github.com/rajan-marasini/ecom-microservice/money"
+	"github.com/rajan-marasini/ecom-microservice/tracing"
 )
 
 type Repository interface {
 	Close()
+	// Ping reports an error when the storage cannot be reached.
+	Ping(ctx context.Context) error
 	PutOrder(ctx context.Context, o Order) error
+	GetOrder(ctx context.Context, id string) (*Order, error)
+	// GetOrderByIdempotencyKey returns the order the account placed with key.
+	GetOrderByIdempotencyKey(ctx context.Context, accountID, key string) (*Order, error)
 	GetOrdersForAccount(ctx context.Context, accountID string) ([]Order, error)
+	// UpdateOrderStatus records change if the order is still in status from,
+	// and returns a FailedPrecondition error if it no longer is.
+	UpdateOrderStatus(ctx context.Context, id string, from Status, change StatusChange) error
 }
 
 type postgresRepository struct {
@@ -19,14 +32,16 @@ type postgresRepository struct {
 }
 
 func NewPostgresRepository(url string) (Repository, error) {
-	db, err := sql.Open("postgres", url)
+	connector, err := pq.NewConnector(url)
 	if err != nil {
 		return nil, err
 	}
+	db := sql.OpenDB(tracing.Connector(connector, "postgresql"))
 	err = db.Ping()
 	if err != nil {
 		return nil, err
 	}
+	metrics.RegisterDB(db, "order")
 
 	return &postgresRepository{db}, nil
 }
@@ -35,7 +50,11 @@ func (r *postgresRepository) Close() {
 	r.db.Close()
 }
 
-func (r *postgresRepository) PutOrder(ctx context.Context, o Order) error {
+func (r *postgresRepository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+func (r *postgresRepository) PutOrder(ctx context.Context, o Order) (err error) {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
@@ -50,24 +69,41 @@ func (r *postgresRepository) PutOrder(ctx context.Context, o Order) error {
 
 	_, err = tx.ExecContext(
 		ctx,
-		"INSERT INTO orders(id, created_at, account_id, total_price) VALUES ($1, $2, $3, $4)",
+		"INSERT INTO orders(id, created_at, account_id, total_price, currency, idempotency_key, status) VALUES ($1, $2, $3, $4, $5, $6, $7)",
 		o.ID,
 		o.CreatedAt,
 		o.AccountID,
-		o.TotalPrice,
+		o.TotalPrice.Amount,
+		o.TotalPrice.Currency,
+		nullString(o.IdempotencyKey),
+		o.Status.String(),
 	)
 	if err != nil {
-		return err
+		return uniqueError(err, o)
+	}
+
+	for _, change := range o.History {
+		if err = insertStatusChange(ctx, tx, o.ID, change); err != nil {
+			return err
+		}
 	}
 
-	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("order_products", "order_id", "product_id", "quantity"))
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(
+		"order_products",
+		"order_id",
+		"product_id",
+		"quantity",
+		"name",
+		"description",
+		"unit_price",
+	))
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
 	for _, p := range o.Products {
-		_, err = stmt.ExecContext(ctx, o.ID, p.ID, p.Quantity)
+		_, err = stmt.ExecContext(ctx, o.ID, p.ID, p.Quantity, p.Name, p.Description, p.Price.Amount)
 		if err != nil {
 			return err
 		}
@@ -81,6 +117,75 @@ func (r *postgresRepository) PutOrder(ctx context.Context, o Order) error {
 	return nil
 }
 
+func (r *postgresRepository) GetOrder(ctx context.Context, id string) (*Order, error) {
+	o := &Order{}
+	var idempotencyKey sql.NullString
+	var status string
+
+	err := r.db.QueryRowContext(
+		ctx,
+		"SELECT id, created_at, account_id, total_price, currency, idempotency_key, status FROM orders WHERE id = $1",
+		id,
+	).Scan(&o.ID, &o.CreatedAt, &o.AccountID, &o.TotalPrice.Amount, &o.TotalPrice.Currency, &idempotencyKey, &status)
+	if err == sql.ErrNoRows {
+		return nil, errs.New(errs.NotFound, "order %s not found", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	o.IdempotencyKey = idempotencyKey.String
+	if o.Status, err = parseStatus(status); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		"SELECT product_id, quantity, name, description, unit_price FROM order_products WHERE order_id = $1 ORDER BY product_id",
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var line orderLine
+		if err := rows.Scan(&line.productID, &line.quantity, &line.name, &line.description, &line.unitPrice); err != nil {
+			return nil, err
+		}
+		o.Products = append(o.Products, line.product(o.TotalPrice.Currency))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	histories, err := r.statusHistories(ctx, "order_id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	o.History = histories[o.ID]
+
+	return o, nil
+}
+
+func (r *postgresRepository) GetOrderByIdempotencyKey(ctx context.Context, accountID, key string) (*Order, error) {
+	var id string
+	err := r.db.QueryRowContext(
+		ctx,
+		"SELECT id FROM orders WHERE account_id = $1 AND idempotency_key = $2",
+		accountID,
+		key,
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, errs.New(errs.NotFound, "no order placed with idempotency key %s", key)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetOrder(ctx, id)
+}
+
 func (r *postgresRepository) GetOrdersForAccount(ctx context.Context, accountID string) ([]Order, error) {
 	rows, err := r.db.QueryContext(
 		ctx,
@@ -88,9 +193,15 @@ func (r *postgresRepository) GetOrdersForAccount(ctx context.Context, accountID
 			o.id, 
 			o.created_at, 
 			o.account_id, 
-			o.total_price::numeric::float8, 
+			o.total_price, 
+			o.currency, 
+			o.idempotency_key, 
+			o.status, 
 			op.product_id, 
-			op.quantity 
+			op.quantity, 
+			op.name, 
+			op.description, 
+			op.unit_price 
 		FROM orders o 
 		JOIN order_products op ON (o.id = op.order_id) 
 		WHERE o.account_id = $1 
@@ -107,21 +218,28 @@ func (r *postgresRepository) GetOrdersForAccount(ctx context.Context, accountID
 
 	for rows.Next() {
 		var (
-			id         string
-			createdAt  time.Time
-			accID      string
-			totalPrice float64
-			productID  string
-			productQty uint32
+			id             string
+			createdAt      time.Time
+			accID          string
+			totalPrice     money.Money
+			idempotencyKey sql.NullString
+			status         string
+			line           orderLine
 		)
 
 		if err := rows.Scan(
 			&id,
 			&createdAt,
 			&accID,
-			&totalPrice,
-			&productID,
-			&productQty,
+			&totalPrice.Amount,
+			&totalPrice.Currency,
+			&idempotencyKey,
+			&status,
+			&line.productID,
+			&line.quantity,
+			&line.name,
+			&line.description,
+			&line.unitPrice,
 		); err != nil {
 			return nil, err
 		}
@@ -131,17 +249,18 @@ func (r *postgresRepository) GetOrdersForAccount(ctx context.Context, accountID
 				orders = append(orders, *lastOrder)
 			}
 			lastOrder = &Order{
-				ID:         id,
-				CreatedAt:  createdAt,
-				AccountID:  accID,
-				TotalPrice: totalPrice,
+				ID:             id,
+				CreatedAt:      createdAt,
+				AccountID:      accID,
+				TotalPrice:     totalPrice,
+				IdempotencyKey: idempotencyKey.String,
+			}
+			if lastOrder.Status, err = parseStatus(status); err != nil {
+				return nil, err
 			}
 		}
 
-		lastOrder.Products = append(lastOrder.Products, OrderedProduct{
-			ID:       productID,
-			Quantity: productQty,
-		})
+		lastOrder.Products = append(lastOrder.Products, line.product(totalPrice.Currency))
 	}
 
 	if lastOrder != nil {
@@ -152,5 +271,137 @@ func (r *postgresRepository) GetOrdersForAccount(ctx context.Context, accountID
 		return nil, err
 	}
 
+	histories, err := r.statusHistories(ctx, "order_id IN (SELECT id FROM orders WHERE account_id = $1)", accountID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range orders {
+		orders[i].History = histories[orders[i].ID]
+	}
+
 	return orders, nil
 }
+
+func (r *postgresRepository) UpdateOrderStatus(ctx context.Context, id string, from Status, change StatusChange) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	res, err := tx.ExecContext(
+		ctx,
+		"UPDATE orders SET status = $1 WHERE id = $2 AND status = $3",
+		change.Status.String(),
+		id,
+		from.String(),
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errs.New(errs.FailedPrecondition, "order %s is no longer %s", id, from)
+	}
+
+	return insertStatusChange(ctx, tx, id, change)
+}
+
+func insertStatusChange(ctx context.Context, tx *sql.Tx, orderID string, change StatusChange) error {
+	_, err := tx.ExecContext(
+		ctx,
+		"INSERT INTO order_status_history(order_id, status, changed_at, actor_id) VALUES ($1, $2, $3, $4)",
+		orderID,
+		change.Status.String(),
+		change.ChangedAt,
+		change.ActorID,
+	)
+	return err
+}
+
+// statusHistories returns the status history of the orders matching where,
+// by order ID and oldest first.
+func (r *postgresRepository) statusHistories(ctx context.Context, where string, args ...any) (map[string][]StatusChange, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		"SELECT order_id, status, changed_at, actor_id FROM order_status_history WHERE "+where+" ORDER BY changed_at, id",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	histories := map[string][]StatusChange{}
+	for rows.Next() {
+		var (
+			orderID string
+			status  string
+			change  StatusChange
+		)
+		if err := rows.Scan(&orderID, &status, &change.ChangedAt, &change.ActorID); err != nil {
+			return nil, err
+		}
+		if change.Status, err = parseStatus(status); err != nil {
+			return nil, err
+		}
+		histories[orderID] = append(histories[orderID], change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return histories, nil
+}
+
+// nullString stores an empty string as NULL, which the unique index on
+// idempotency keys allows more than once.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// uniqueError reports which unique constraint a write of o violated.
+func uniqueError(err error, o Order) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != "23505" {
+		return err
+	}
+
+	if pqErr.Constraint == "orders_account_id_idempotency_key_key" {
+		return errs.New(errs.AlreadyExists, "an order was already placed with idempotency key %s", o.IdempotencyKey)
+	}
+	return errs.New(errs.AlreadyExists, "order %s already exists", o.ID)
+}
+
+// orderLine is a scanned order_products row. The snapshot columns are NULL
+// for lines written before they were introduced.
+type orderLine struct {
+	productID   string
+	quantity    uint32
+	name        sql.NullString
+	description sql.NullString
+	unitPrice   sql.NullInt64
+}
+
+func (l orderLine) product(currency string) OrderedProduct {
+	p := OrderedProduct{
+		ID:          l.productID,
+		Quantity:    l.quantity,
+		Name:        l.name.String,
+		Description: l.description.String,
+	}
+	if l.unitPrice.Valid {
+		p.Price = money.New(l.unitPrice.Int64, currency)
+	}
+
+	return p
+}
@@ -1,15 +1,24 @@
+//go:generate protoc -I . -I .. --go_out=./pb --go_opt=paths=source_relative --go-grpc_out=./pb --go-grpc_opt=paths=source_relative order.proto
 package order
 
 import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
-	"net"
+	"log/slog"
 
 	"github.com/rajan-marasini/ecom-microservice/account"
+	"github.com/rajan-marasini/ecom-microservice/auth"
 	"github.com/rajan-marasini/ecom-microservice/catalog"
+	"github.com/rajan-marasini/ecom-microservice/errs"
+	"github.com/rajan-marasini/ecom-microservice/grpcclient"
+	"github.com/rajan-marasini/ecom-microservice/grpcserver"
+	"github.com/rajan-marasini/ecom-microservice/logging"
+	"github.com/rajan-marasini/ecom-microservice/metrics"
+	"github.com/rajan-marasini/ecom-microservice/money"
+	"github.com/rajan-marasini/ecom-microservice/mtls"
 	"github.com/rajan-marasini/ecom-microservice/order/pb"
+	"github.com/rajan-marasini/ecom-microservice/tracing"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 )
@@ -21,26 +30,30 @@ type grpcServer struct {
 	pb.UnimplementedOrderServiceServer
 }
 
-func ListenGRPC(s Service, accountURL, catalogURL string, port int) error {
-	accountClient, err := account.NewClient(accountURL)
+// ListenGRPC serves s on port. The server reports itself healthy while checks
+// pass and the account and catalog services are serving. The clients for
+// those, made with clientOpts, are closed after the server shuts down.
+func ListenGRPC(ctx context.Context, s Service, accountURL, catalogURL string, port int, creds *mtls.Credentials, clientOpts grpcclient.Options, checks ...grpcserver.Check) (*grpcserver.Server, error) {
+	accountClient, err := account.NewClient(accountURL, clientOpts)
 	if err != nil {
-		return err
-	}
-
-	catalogClient, err := catalog.NewClient(catalogURL)
-	if err != nil {
-		accountClient.Close()
-		return err
+		return nil, err
 	}
 
-	list, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	catalogClient, err := catalog.NewClient(catalogURL, clientOpts)
 	if err != nil {
 		accountClient.Close()
-		catalogClient.Close()
-		return err
+		return nil, err
 	}
 
-	serv := grpc.NewServer()
+	serv := grpc.NewServer(
+		creds.ServerOption(),
+		tracing.ServerOption(),
+		grpc.ChainUnaryInterceptor(
+			metrics.UnaryServerInterceptor,
+			errs.UnaryServerInterceptor,
+			logging.UnaryServerInterceptor,
+		),
+	)
 
 	pb.RegisterOrderServiceServer(serv, &grpcServer{
 		service:       s,
@@ -49,135 +62,270 @@ func ListenGRPC(s Service, accountURL, catalogURL string, port int) error {
 	})
 
 	reflection.Register(serv)
+	checks = append(checks, accountClient.Health, catalogClient.Health)
+	grpcserver.RegisterHealth(ctx, serv, pb.OrderService_ServiceDesc.ServiceName, checks...)
 
-	return serv.Serve(list)
+	srv, err := grpcserver.Start(ctx, serv, port, accountClient.Close, catalogClient.Close)
+	if err != nil {
+		accountClient.Close()
+		catalogClient.Close()
+		return nil, err
+	}
+	return srv, nil
 }
 
 func (s *grpcServer) PostOrder(ctx context.Context, r *pb.PostOrderRequest) (*pb.PostOrderResponse, error) {
 
 	_, err := s.accountClient.GetAccountByID(ctx, r.AccountId)
 	if err != nil {
-		log.Println("Error getting account: ", err)
-		return nil, errors.New("Account not found")
+		slog.WarnContext(ctx, "getting account failed", "account_id", r.AccountId, "error", err)
+		return nil, err
 	}
 
-	var productIDs []string
-	// productIDs := []string{}
+	// A retried request is answered from the order it placed before its
+	// products are resolved, since they may have been archived since.
+	if r.IdempotencyKey != "" {
+		requested := []OrderedProduct{}
+		for _, rp := range r.Products {
+			requested = append(requested, OrderedProduct{ID: rp.ProductId, Quantity: rp.Quantity})
+		}
+		order, err := s.service.ReplayOrder(ctx, r.AccountId, r.IdempotencyKey, requested)
+		if err == nil {
+			return &pb.PostOrderResponse{Order: orderToProto(*order)}, nil
+		}
+		if !errors.Is(err, errs.ErrNotFound) {
+			return nil, err
+		}
+	}
 
-	orderedProducts, err := s.catalogClient.GetProducts(ctx, 0, 0, productIDs, "")
+	products, err := s.resolveProducts(ctx, r.Products)
 	if err != nil {
-		log.Println("Error getting products: ", err)
-		return nil, errors.New("products not found")
+		return nil, err
 	}
 
-	var products []OrderedProduct
+	order, err := s.service.PostOrder(ctx, r.AccountId, r.IdempotencyKey, products)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.PostOrderResponse{
+		Order: orderToProto(*order),
+	}, nil
+}
+
+// resolveProducts looks up every requested product in the catalog and returns
+// them priced, in request order. Duplicate, unknown and archived products are
+// reported together as one InvalidArgument error with a field violation per
+// offending item. Items without an ID are passed through unpriced so that
+// service validation reports them at the right index.
+func (s *grpcServer) resolveProducts(ctx context.Context, requested []*pb.PostOrderRequest_OrderProduct) ([]OrderedProduct, error) {
+	violations := []errs.Violation{}
+	violate := func(i int, description string) {
+		violations = append(violations, errs.Violation{
+			Field:       fmt.Sprintf("products[%d].productId", i),
+			Description: description,
+		})
+	}
 
-	for _, p := range orderedProducts {
+	productIDs := []string{}
+	seen := map[string]bool{}
+	for i, rp := range requested {
+		if rp.ProductId == "" {
+			continue
+		}
+		if seen[rp.ProductId] {
+			violate(i, fmt.Sprintf("product %s is listed more than once", rp.ProductId))
+			continue
+		}
+		seen[rp.ProductId] = true
+		productIDs = append(productIDs, rp.ProductId)
+	}
 
-		product := OrderedProduct{
-			ID:          p.ID,
-			Quantity:    0,
-			Price:       p.Price,
-			Name:        p.Name,
-			Description: p.Name,
+	catalogProducts := map[string]catalog.Product{}
+	if len(productIDs) != 0 {
+		found, err := s.catalogClient.GetProducts(ctx, 0, 0, productIDs, "", catalog.SortNewest)
+		if err != nil {
+			slog.WarnContext(ctx, "getting products failed", "product_ids", productIDs, "error", err)
+			return nil, err
+		}
+		for _, p := range found {
+			catalogProducts[p.ID] = p
 		}
+	}
 
-		for _, rp := range r.Products {
-			if rp.ProductId == p.ID {
-				product.Quantity = rp.Quantity
-				break
+	products := []OrderedProduct{}
+	added := map[string]bool{}
+	for i, rp := range requested {
+		switch {
+		case rp.ProductId == "":
+			products = append(products, OrderedProduct{Quantity: rp.Quantity})
+		case added[rp.ProductId]:
+			// Already reported as a duplicate above.
+		default:
+			added[rp.ProductId] = true
+
+			p, ok := catalogProducts[rp.ProductId]
+			if !ok {
+				violate(i, fmt.Sprintf("product %s does not exist", rp.ProductId))
+				continue
+			}
+			if p.Archived {
+				violate(i, fmt.Sprintf("product %s is archived", rp.ProductId))
+				continue
 			}
-		}
 
-		if product.Quantity != 0 {
-			products = append(products, product)
+			products = append(products, OrderedProduct{
+				ID:          p.ID,
+				Name:        p.Name,
+				Description: p.Description,
+				Price:       p.Price,
+				Quantity:    rp.Quantity,
+			})
 		}
 	}
 
-	order, err := s.service.PostOrder(ctx, r.AccountId, products)
-	if err != nil {
-		log.Println("Error posting order", err)
-		return nil, errors.New("Could not post order")
+	if len(violations) != 0 {
+		return nil, errs.Invalid("invalid order products", violations...)
 	}
 
-	orderProto := &pb.Order{
-		Id:         order.ID,
-		AccountId:  order.AccountID,
-		TotalPrice: order.TotalPrice,
-		Products:   []*pb.Order_OrderProduct{},
-	}
-	orderProto.CreatedAt, _ = order.CreatedAt.MarshalBinary()
-	for _, p := range order.Products {
-		orderProto.Products = append(orderProto.Products, &pb.Order_OrderProduct{
-			Id:          p.ID,
-			Name:        p.Name,
-			Description: p.Description,
-			Price:       float64(p.Price),
-			Quantity:    p.Quantity,
-		})
+	return products, nil
+}
+
+func (s *grpcServer) GetOrder(ctx context.Context, r *pb.GetOrderRequest) (*pb.GetOrderResponse, error) {
+	o, err := s.service.GetOrder(ctx, r.Id)
+	if err != nil {
+		return nil, err
 	}
 
-	return &pb.PostOrderResponse{
-		Order: orderProto,
-	}, nil
+	s.enrichOrders(ctx, []Order{*o})
+
+	return &pb.GetOrderResponse{Order: orderToProto(*o)}, nil
 }
 
 func (s *grpcServer) GetOrdersForAccount(ctx context.Context, r *pb.GetOrdersForAccountRequest) (*pb.GetOrdersForAccountResponse, error) {
 	accountOrders, err := s.service.GetOrdersForAccount(ctx, r.AccountId)
 	if err != nil {
-		log.Println(err)
 		return nil, err
 	}
 
-	productIDMap := map[string]bool{}
+	s.enrichOrders(ctx, accountOrders)
+
+	orders := []*pb.Order{}
 	for _, o := range accountOrders {
+		orders = append(orders, orderToProto(o))
+	}
+
+	return &pb.GetOrdersForAccountResponse{Orders: orders}, nil
+}
+
+func (s *grpcServer) UpdateOrderStatus(ctx context.Context, r *pb.UpdateOrderStatusRequest) (*pb.UpdateOrderStatusResponse, error) {
+	caller, ok := auth.FromIncomingContext(ctx)
+	if !ok {
+		return nil, errs.New(errs.Unauthenticated, "authentication required")
+	}
+
+	status := Status(r.Status)
+	if !caller.HasRole(auth.RoleMerchant) {
+		if status != StatusCancelled {
+			return nil, errs.New(errs.PermissionDenied, "requires role %s", auth.RoleMerchant)
+		}
+		// Orders of other accounts are reported like missing ones, so their
+		// IDs can't be probed.
+		o, err := s.service.GetOrder(ctx, r.Id)
+		if errs.KindOf(err) == errs.NotFound || (err == nil && o.AccountID != caller.AccountID) {
+			return nil, errs.New(errs.NotFound, "order %s not found", r.Id)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	o, err := s.service.UpdateOrderStatus(ctx, r.Id, status, caller.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.enrichOrders(ctx, []Order{*o})
+
+	return &pb.UpdateOrderStatusResponse{Order: orderToProto(*o)}, nil
+}
+
+// enrichOrders fills in name, description and price from the catalog for
+// order lines stored without a purchase-time snapshot. Enrichment is best
+// effort: if the catalog can't be reached, those lines are returned as
+// stored.
+func (s *grpcServer) enrichOrders(ctx context.Context, orders []Order) {
+	productIDMap := map[string]bool{}
+	for _, o := range orders {
 		for _, p := range o.Products {
-			productIDMap[p.ID] = true
+			if !p.hasSnapshot() {
+				productIDMap[p.ID] = true
+			}
 		}
 	}
 
+	if len(productIDMap) == 0 {
+		return
+	}
+
 	var productIDs []string
 	for id := range productIDMap {
 		productIDs = append(productIDs, id)
 	}
 
-	products, err := s.catalogClient.GetProducts(ctx, 0, 0, productIDs, "")
+	products, err := s.catalogClient.GetProducts(ctx, 0, 0, productIDs, "", catalog.SortNewest)
 	if err != nil {
-		log.Println("Error getting account products", err)
-		return nil, err
+		slog.WarnContext(ctx, "getting order products failed, returning them as stored", "error", err)
+		return
 	}
 
-	orders := []*pb.Order{}
-	for _, o := range accountOrders {
-		op := &pb.Order{
-			AccountId:  o.AccountID,
-			Id:         o.ID,
-			TotalPrice: o.TotalPrice,
-			Products:   []*pb.Order_OrderProduct{},
-		}
-		op.CreatedAt, _ = o.CreatedAt.MarshalBinary()
-
-		for _, product := range o.Products {
-			for _, p := range products {
-				if p.ID == product.ID {
-					product.Name = p.Name
-					product.Description = p.Description
-					product.Price = p.Price
-					break
-				}
+	catalogProducts := map[string]catalog.Product{}
+	for _, p := range products {
+		catalogProducts[p.ID] = p
+	}
 
+	for _, o := range orders {
+		for i, product := range o.Products {
+			p, ok := catalogProducts[product.ID]
+			if product.hasSnapshot() || !ok {
+				continue
 			}
+			o.Products[i].Name = p.Name
+			o.Products[i].Description = p.Description
+			o.Products[i].Price = p.Price
+		}
+	}
+}
 
-			op.Products = append(op.Products, &pb.Order_OrderProduct{
-				Id:          product.ID,
-				Name:        product.Name,
-				Description: product.Description,
-				Price:       product.Price,
-				Quantity:    product.Quantity,
-			})
+func orderToProto(o Order) *pb.Order {
+	op := &pb.Order{
+		AccountId:  o.AccountID,
+		Id:         o.ID,
+		TotalPrice: money.ToProto(o.TotalPrice),
+		Products:   []*pb.Order_OrderProduct{},
+		Status:     pb.OrderStatus(o.Status),
+		History:    []*pb.StatusChange{},
+	}
+	op.CreatedAt, _ = o.CreatedAt.MarshalBinary()
+
+	for _, c := range o.History {
+		change := &pb.StatusChange{
+			Status:  pb.OrderStatus(c.Status),
+			ActorId: c.ActorID,
 		}
-		orders = append(orders, op)
+		change.ChangedAt, _ = c.ChangedAt.MarshalBinary()
+		op.History = append(op.History, change)
 	}
 
-	return &pb.GetOrdersForAccountResponse{Orders: orders}, nil
+	for _, p := range o.Products {
+		op.Products = append(op.Products, &pb.Order_OrderProduct{
+			Id:          p.ID,
+			Name:        p.Name,
+			Description: p.Description,
+			Price:       money.ToProto(p.Price),
+			Quantity:    p.Quantity,
+		})
+	}
+
+	return op
 }
@@ -0,0 +1,289 @@
+package order
+
+import (
+	"context"
+	"net"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+
+	accountpb "github.com/rajan-marasini/ecom-microservice/account/pb"
+	"github.com/rajan-marasini/ecom-microservice/auth"
+	catalogpb "github.com/rajan-marasini/ecom-microservice/catalog/pb"
+	"github.com/rajan-marasini/ecom-microservice/errs"
+	"github.com/rajan-marasini/ecom-microservice/grpcclient"
+	"github.com/rajan-marasini/ecom-microservice/money"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type stubAccountServer struct {
+	accountpb.UnimplementedAccountServiceServer
+	ids []string
+}
+
+func (s *stubAccountServer) GetAccountByID(ctx context.Context, r *accountpb.GetAccountByIDRequest) (*accountpb.GetAccountByIDResponse, error) {
+	if !slices.Contains(s.ids, r.Id) {
+		return nil, status.Errorf(codes.NotFound, "account %s not found", r.Id)
+	}
+	return &accountpb.GetAccountByIDResponse{Account: &accountpb.Account{Id: r.Id}}, nil
+}
+
+type stubCatalogServer struct {
+	catalogpb.UnimplementedCatalogServiceServer
+	mu       sync.Mutex
+	products []*catalogpb.Product
+}
+
+func (s *stubCatalogServer) GetProducts(ctx context.Context, r *catalogpb.GetProductsRequest) (*catalogpb.GetProductsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := &catalogpb.GetProductsResponse{}
+	for _, p := range s.products {
+		if slices.Contains(r.Ids, p.Id) {
+			res.Products = append(res.Products, p)
+		}
+	}
+	return res, nil
+}
+
+func (s *stubCatalogServer) archive(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, p := range s.products {
+		if p.Id == id {
+			p.Archived = true
+		}
+	}
+}
+
+// serve runs a gRPC server with the services registered by register on a
+// local port and returns its address.
+func serve(t *testing.T, register func(*grpc.Server)) string {
+	t.Helper()
+
+	list, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serv := grpc.NewServer()
+	register(serv)
+	go serv.Serve(list)
+	t.Cleanup(serv.Stop)
+
+	return list.Addr().String()
+}
+
+func freePort(t *testing.T) int {
+	t.Helper()
+
+	list, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer list.Close()
+
+	return list.Addr().(*net.TCPAddr).Port
+}
+
+// newTestClient serves the order service, backed by stub account and catalog
+// services, and returns a client for it along with the catalog stub.
+func newTestClient(t *testing.T) (*Client, *stubCatalogServer) {
+	t.Helper()
+
+	accountURL := serve(t, func(s *grpc.Server) {
+		accountpb.RegisterAccountServiceServer(s, &stubAccountServer{ids: []string{"alice"}})
+	})
+	catalogServer := &stubCatalogServer{products: []*catalogpb.Product{
+		{Id: "mug", Name: "Mug", Description: "Holds coffee", Price: money.ToProto(money.New(1250, "USD"))},
+		{Id: "tea", Name: "Tea", Description: "Loose leaf", Price: money.ToProto(money.New(799, "USD"))},
+		{Id: "vase", Name: "Vase", Description: "Out of stock", Price: money.ToProto(money.New(3000, "USD")), Archived: true},
+	}}
+	catalogURL := serve(t, func(s *grpc.Server) {
+		catalogpb.RegisterCatalogServiceServer(s, catalogServer)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	port := freePort(t)
+	srv, err := ListenGRPC(ctx, NewService(NewMemoryRepository()), accountURL, catalogURL, port, nil, grpcclient.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cancel()
+		srv.Shutdown(context.Background())
+	})
+
+	c, err := NewClient(net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), grpcclient.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(c.Close)
+
+	return c, catalogServer
+}
+
+func TestPostOrder(t *testing.T) {
+	c, _ := newTestClient(t)
+
+	o, err := c.PostOrder(context.Background(), "alice", "", []OrderedProduct{
+		{ID: "tea", Quantity: 2},
+		{ID: "mug", Quantity: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []OrderedProduct{
+		{ID: "tea", Name: "Tea", Description: "Loose leaf", Price: money.New(799, "USD"), Quantity: 2},
+		{ID: "mug", Name: "Mug", Description: "Holds coffee", Price: money.New(1250, "USD"), Quantity: 1},
+	}
+	if !slices.Equal(o.Products, want) {
+		t.Errorf("products = %+v, want %+v", o.Products, want)
+	}
+	if total := money.New(2848, "USD"); o.TotalPrice != total {
+		t.Errorf("total = %v, want %v", o.TotalPrice, total)
+	}
+	if o.AccountID != "alice" || o.Status != StatusPending {
+		t.Errorf("order = %+v, want a pending order of alice", o)
+	}
+}
+
+func TestPostOrderRejectsProducts(t *testing.T) {
+	c, _ := newTestClient(t)
+
+	tests := []struct {
+		name       string
+		products   []OrderedProduct
+		violations []errs.Violation
+	}{
+		{
+			name:     "duplicate",
+			products: []OrderedProduct{{ID: "mug", Quantity: 1}, {ID: "tea", Quantity: 1}, {ID: "mug", Quantity: 2}},
+			violations: []errs.Violation{
+				{Field: "products[2].productId", Description: "product mug is listed more than once"},
+			},
+		},
+		{
+			name:     "unknown",
+			products: []OrderedProduct{{ID: "mug", Quantity: 1}, {ID: "lamp", Quantity: 1}},
+			violations: []errs.Violation{
+				{Field: "products[1].productId", Description: "product lamp does not exist"},
+			},
+		},
+		{
+			name:     "unknown and duplicate",
+			products: []OrderedProduct{{ID: "lamp", Quantity: 1}, {ID: "tea", Quantity: 1}, {ID: "lamp", Quantity: 1}, {ID: "tea", Quantity: 3}},
+			violations: []errs.Violation{
+				{Field: "products[2].productId", Description: "product lamp is listed more than once"},
+				{Field: "products[3].productId", Description: "product tea is listed more than once"},
+				{Field: "products[0].productId", Description: "product lamp does not exist"},
+			},
+		},
+		{
+			name:     "archived",
+			products: []OrderedProduct{{ID: "mug", Quantity: 1}, {ID: "vase", Quantity: 1}},
+			violations: []errs.Violation{
+				{Field: "products[1].productId", Description: "product vase is archived"},
+			},
+		},
+		{
+			name:     "missing id",
+			products: []OrderedProduct{{ID: "mug", Quantity: 1}, {Quantity: 1}},
+			violations: []errs.Violation{
+				{Field: "products[1].productId", Description: "must not be blank"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := c.PostOrder(context.Background(), "alice", "", tt.products)
+			if errs.KindOf(err) != errs.InvalidArgument {
+				t.Fatalf("err = %v, want InvalidArgument", err)
+			}
+			if violations := errs.ViolationsOf(err); !slices.Equal(violations, tt.violations) {
+				t.Errorf("violations = %+v, want %+v", violations, tt.violations)
+			}
+		})
+	}
+}
+
+func TestPostOrderUnknownAccount(t *testing.T) {
+	c, _ := newTestClient(t)
+
+	_, err := c.PostOrder(context.Background(), "bob", "", []OrderedProduct{{ID: "mug", Quantity: 1}})
+	if errs.KindOf(err) != errs.NotFound {
+		t.Fatalf("err = %v, want NotFound", err)
+	}
+}
+
+func TestPostOrderReplaysArchivedProducts(t *testing.T) {
+	c, catalogServer := newTestClient(t)
+	products := []OrderedProduct{{ID: "mug", Quantity: 1}}
+
+	placed, err := c.PostOrder(context.Background(), "alice", "checkout-1", products)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	catalogServer.archive("mug")
+
+	replayed, err := c.PostOrder(context.Background(), "alice", "checkout-1", products)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed.ID != placed.ID {
+		t.Errorf("replayed order %s, want %s", replayed.ID, placed.ID)
+	}
+
+	_, err = c.PostOrder(context.Background(), "alice", "checkout-2", products)
+	if errs.KindOf(err) != errs.InvalidArgument {
+		t.Errorf("err = %v, want InvalidArgument", err)
+	}
+}
+
+func TestUpdateOrderStatusAsCustomer(t *testing.T) {
+	c, _ := newTestClient(t)
+	as := func(accountID string) context.Context {
+		return auth.NewOutgoingContext(context.Background(), auth.Identity{AccountID: accountID, Roles: []string{auth.RoleCustomer}})
+	}
+
+	o, err := c.PostOrder(context.Background(), "alice", "", []OrderedProduct{{ID: "mug", Quantity: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		id     string
+		status Status
+		want   errs.Kind
+	}{
+		{name: "confirm own order", ctx: as("alice"), id: o.ID, status: StatusConfirmed, want: errs.PermissionDenied},
+		{name: "cancel another account's order", ctx: as("bob"), id: o.ID, status: StatusCancelled, want: errs.NotFound},
+		{name: "cancel missing order", ctx: as("bob"), id: "missing", status: StatusCancelled, want: errs.NotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := c.UpdateOrderStatus(tt.ctx, tt.id, tt.status)
+			if err == nil || errs.KindOf(err) != tt.want {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	cancelled, err := c.UpdateOrderStatus(as("alice"), o.ID, StatusCancelled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancelled.Status != StatusCancelled {
+		t.Errorf("status = %v, want %v", cancelled.Status, StatusCancelled)
+	}
+}
@@ -2,32 +2,63 @@ package order
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"github.com/rajan-marasini/ecom-microservice/errs"
+	"github.com/rajan-marasini/ecom-microservice/money"
+	"github.com/rajan-marasini/ecom-microservice/validation"
 	"github.com/segmentio/ksuid"
 )
 
 type Service interface {
-	PostOrder(ctx context.Context, accountID string, products []OrderedProduct) (*Order, error)
+	// PostOrder places an order. When idempotencyKey is set and the account
+	// placed an order with it before, that order is returned instead, or a
+	// FailedPrecondition error if its products differ.
+	PostOrder(ctx context.Context, accountID, idempotencyKey string, products []OrderedProduct) (*Order, error)
+	// ReplayOrder returns the order the account placed with idempotencyKey,
+	// failing like PostOrder if its products differ, or a NotFound error if
+	// there is none.
+	ReplayOrder(ctx context.Context, accountID, idempotencyKey string, products []OrderedProduct) (*Order, error)
+	GetOrder(ctx context.Context, id string) (*Order, error)
 	GetOrdersForAccount(ctx context.Context, accountID string) ([]Order, error)
+	// UpdateOrderStatus moves an order to status on behalf of the account
+	// actorID, or returns a FailedPrecondition error if its current status
+	// doesn't allow that.
+	UpdateOrderStatus(ctx context.Context, id string, status Status, actorID string) (*Order, error)
 }
 
 type Order struct {
 	ID         string
 	CreatedAt  time.Time
-	TotalPrice float64
+	TotalPrice money.Money
 	AccountID  string
 	Products   []OrderedProduct
+	// IdempotencyKey is the key the order was placed with, if any. It is
+	// unique per account.
+	IdempotencyKey string
+	Status         Status
+	// History lists every status the order has had, oldest first, starting
+	// with pending when it was placed.
+	History []StatusChange
 }
 
+// OrderedProduct is an order line. Name, Description and Price are a
+// snapshot of the catalog product taken when the order was placed.
 type OrderedProduct struct {
 	ID          string
 	Name        string
 	Description string
-	Price       float64
+	Price       money.Money
 	Quantity    uint32
 }
 
+// hasSnapshot reports whether the line carries its purchase-time details.
+// Lines stored before snapshots were introduced don't.
+func (p OrderedProduct) hasSnapshot() bool {
+	return p.Price.Currency != ""
+}
+
 type orderService struct {
 	repository Repository
 }
@@ -36,24 +67,134 @@ func NewService(r Repository) Service {
 	return &orderService{r}
 }
 
-func (s orderService) PostOrder(ctx context.Context, accountID string, products []OrderedProduct) (*Order, error) {
+func (s orderService) PostOrder(ctx context.Context, accountID, idempotencyKey string, products []OrderedProduct) (*Order, error) {
+	if err := validation.Validate(
+		validation.Field("accountId", accountID, validation.NotBlank),
+		validation.Field("idempotencyKey", idempotencyKey, validation.MaxLength(255)),
+		validation.Field("products", products, validation.NotEmpty),
+		validation.Each("products", products, func(p OrderedProduct) []validation.Check {
+			return []validation.Check{
+				validation.Field("productId", p.ID, validation.NotBlank),
+				validation.Field("quantity", p.Quantity, validation.Positive),
+			}
+		}),
+	); err != nil {
+		return nil, err
+	}
+
+	if idempotencyKey != "" {
+		o, err := s.ReplayOrder(ctx, accountID, idempotencyKey, products)
+		if !errors.Is(err, errs.ErrNotFound) {
+			return o, err
+		}
+	}
+
 	o := &Order{
-		ID:        ksuid.New().String(),
-		CreatedAt: time.Now().UTC(),
-		AccountID: accountID,
-		Products:  products,
+		ID:             ksuid.New().String(),
+		CreatedAt:      time.Now().UTC(),
+		AccountID:      accountID,
+		Products:       products,
+		IdempotencyKey: idempotencyKey,
+		Status:         StatusPending,
 	}
-	o.TotalPrice = 0.0
+	o.History = []StatusChange{{Status: StatusPending, ChangedAt: o.CreatedAt, ActorID: accountID}}
+	o.TotalPrice = money.New(0, products[0].Price.Currency)
 	for _, p := range products {
-		o.TotalPrice += float64(p.Price) * float64(p.Quantity)
+		line, err := p.Price.Mul(int64(p.Quantity))
+		if err != nil {
+			return nil, err
+		}
+		if o.TotalPrice, err = o.TotalPrice.Add(line); err != nil {
+			return nil, err
+		}
 	}
 
 	if err := s.repository.PutOrder(ctx, *o); err != nil {
+		if idempotencyKey == "" || !errors.Is(err, errs.ErrAlreadyExists) {
+			return nil, err
+		}
+		// A concurrent request with the same key placed its order first.
+		placed, getErr := s.repository.GetOrderByIdempotencyKey(ctx, accountID, idempotencyKey)
+		if getErr != nil {
+			return nil, err
+		}
+		return replayOrder(placed, products)
+	}
+	recordOrderPlaced(*o)
+	return o, nil
+}
+
+func (s orderService) ReplayOrder(ctx context.Context, accountID, idempotencyKey string, products []OrderedProduct) (*Order, error) {
+	o, err := s.repository.GetOrderByIdempotencyKey(ctx, accountID, idempotencyKey)
+	if err != nil {
 		return nil, err
 	}
+	return replayOrder(o, products)
+}
+
+// replayOrder returns the order placed earlier with an idempotency key if it
+// has the requested products and quantities, in any order. Prices aren't
+// compared: they come from the catalog, not the client, and may have changed
+// since.
+func replayOrder(o *Order, products []OrderedProduct) (*Order, error) {
+	quantities := map[string]uint32{}
+	for _, p := range o.Products {
+		quantities[p.ID] = p.Quantity
+	}
+
+	same := len(o.Products) == len(products)
+	for _, p := range products {
+		if q, ok := quantities[p.ID]; !ok || q != p.Quantity {
+			same = false
+		}
+	}
+	if !same {
+		return nil, errs.New(errs.FailedPrecondition, "idempotency key %s was already used for order %s with different products", o.IdempotencyKey, o.ID)
+	}
+
 	return o, nil
 }
 
+func (s orderService) GetOrder(ctx context.Context, id string) (*Order, error) {
+	return s.repository.GetOrder(ctx, id)
+}
+
 func (s orderService) GetOrdersForAccount(ctx context.Context, accountID string) ([]Order, error) {
 	return s.repository.GetOrdersForAccount(ctx, accountID)
 }
+
+func (s orderService) UpdateOrderStatus(ctx context.Context, id string, status Status, actorID string) (*Order, error) {
+	if err := validation.Validate(
+		validation.Field("id", id, validation.NotBlank),
+		validation.Field("status", status, func(s Status) string {
+			if _, ok := statusNames[s]; !ok {
+				return "must be a known status"
+			}
+			return ""
+		}),
+	); err != nil {
+		return nil, err
+	}
+
+	o, err := s.repository.GetOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !o.Status.CanBecome(status) {
+		return nil, errs.New(errs.FailedPrecondition, "order %s is %s and cannot become %s", id, o.Status, status)
+	}
+
+	change := StatusChange{
+		Status:    status,
+		ChangedAt: time.Now().UTC(),
+		ActorID:   actorID,
+	}
+	if err := s.repository.UpdateOrderStatus(ctx, id, o.Status, change); err != nil {
+		return nil, err
+	}
+
+	o.Status = status
+	o.History = append(o.History, change)
+	return o, nil
+}
@@ -0,0 +1,69 @@
+package order
+
+import (
+	"slices"
+	"time"
+
+	"github.com/rajan-marasini/ecom-microservice/errs"
+)
+
+// Status is the lifecycle state of an order. Values match pb.OrderStatus.
+type Status int
+
+const (
+	StatusPending Status = iota
+	StatusConfirmed
+	StatusPaid
+	StatusShipped
+	StatusDelivered
+	StatusCancelled
+	StatusRefunded
+)
+
+var statusNames = map[Status]string{
+	StatusPending:   "pending",
+	StatusConfirmed: "confirmed",
+	StatusPaid:      "paid",
+	StatusShipped:   "shipped",
+	StatusDelivered: "delivered",
+	StatusCancelled: "cancelled",
+	StatusRefunded:  "refunded",
+}
+
+func (s Status) String() string {
+	return statusNames[s]
+}
+
+func parseStatus(name string) (Status, error) {
+	for s, n := range statusNames {
+		if n == name {
+			return s, nil
+		}
+	}
+	return 0, errs.New(errs.Internal, "unknown order status %q", name)
+}
+
+// transitions lists the statuses an order may move to from each status. An
+// order can be cancelled until it is paid for; after that its payment is
+// refunded instead. Cancelled and refunded are final.
+var transitions = map[Status][]Status{
+	StatusPending:   {StatusConfirmed, StatusCancelled},
+	StatusConfirmed: {StatusPaid, StatusCancelled},
+	StatusPaid:      {StatusShipped, StatusRefunded},
+	StatusShipped:   {StatusDelivered},
+	StatusDelivered: {StatusRefunded},
+}
+
+// CanBecome reports whether an order in status s may move to next.
+func (s Status) CanBecome(next Status) bool {
+	return slices.Contains(transitions[s], next)
+}
+
+// StatusChange is an entry of an order's status history.
+type StatusChange struct {
+	Status    Status
+	ChangedAt time.Time
+	// ActorID is the account that made the change. For the initial pending
+	// status it is the account the order was placed for.
+	ActorID string
+}
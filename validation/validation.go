@@ -0,0 +1,128 @@
+// Package validation checks service inputs against declarative per-field
+// rules and reports every failure as an errs.Violation.
+//
+//	err := validation.Validate(
+//		validation.Field("name", name, validation.NotBlank, validation.MaxLength(24)),
+//	)
+package validation
+
+import (
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rajan-marasini/ecom-microservice/errs"
+)
+
+// Rule checks a value and returns a description of what is wrong with it, or
+// an empty string if it is valid.
+type Rule[T any] func(value T) string
+
+// Check is the result of validating one field.
+type Check func() []errs.Violation
+
+// Field applies rules to value in order, stopping at the first failure.
+func Field[T any](name string, value T, rules ...Rule[T]) Check {
+	return func() []errs.Violation {
+		for _, rule := range rules {
+			if description := rule(value); description != "" {
+				return []errs.Violation{{Field: name, Description: description}}
+			}
+		}
+		return nil
+	}
+}
+
+// Each validates every element of values with the checks returned by fn.
+// Field names given to fn are prefixed with name and the element index.
+func Each[T any](name string, values []T, fn func(value T) []Check) Check {
+	return func() []errs.Violation {
+		var violations []errs.Violation
+		for i, value := range values {
+			for _, check := range fn(value) {
+				for _, v := range check() {
+					v.Field = fmt.Sprintf("%s[%d].%s", name, i, v.Field)
+					violations = append(violations, v)
+				}
+			}
+		}
+		return violations
+	}
+}
+
+// Validate runs checks and returns an InvalidArgument error listing every
+// violation, or nil when there are none.
+func Validate(checks ...Check) error {
+	var violations []errs.Violation
+	for _, check := range checks {
+		violations = append(violations, check()...)
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+
+	return errs.Invalid("invalid argument", violations...)
+}
+
+func NotBlank(value string) string {
+	if strings.TrimSpace(value) == "" {
+		return "must not be blank"
+	}
+	return ""
+}
+
+func MinLength(n int) Rule[string] {
+	return func(value string) string {
+		if utf8.RuneCountInString(value) < n {
+			return fmt.Sprintf("must be at least %d characters", n)
+		}
+		return ""
+	}
+}
+
+func MaxLength(n int) Rule[string] {
+	return func(value string) string {
+		if utf8.RuneCountInString(value) > n {
+			return fmt.Sprintf("must be at most %d characters", n)
+		}
+		return ""
+	}
+}
+
+func Positive[T int | int32 | int64 | uint32 | uint64 | float64](value T) string {
+	if value <= 0 {
+		return "must be greater than zero"
+	}
+	return ""
+}
+
+func NotEmpty[T any](values []T) string {
+	if len(values) == 0 {
+		return "must not be empty"
+	}
+	return ""
+}
+
+// Email accepts a bare address such as "alice@example.com", without a
+// display name.
+func Email(value string) string {
+	a, err := mail.ParseAddress(value)
+	if err != nil || a.Name != "" || a.Address != value {
+		return "must be a valid email address"
+	}
+	return ""
+}
+
+var phoneNumber = regexp.MustCompile(`^\+?[1-9][0-9]{6,14}$`)
+
+// Phone accepts E.164 style numbers: an optional + followed by 7 to 15
+// digits.
+func Phone(value string) string {
+	if !phoneNumber.MatchString(value) {
+		return "must be a valid phone number"
+	}
+	return ""
+}